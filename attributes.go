@@ -0,0 +1,474 @@
+package gowrite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// TypedAttribute is implemented by the attribute models of each attribute type. The
+// base *Attribute implements it too and stands for types this package does not model.
+type TypedAttribute interface {
+	// Base returns the fields shared by all attribute types.
+	Base() *Attribute
+	// Validate checks the attribute before it is sent to the server.
+	Validate() error
+	// createRequest returns the path segment and body of the create request.
+	createRequest() (string, map[string]interface{})
+}
+
+// RelationType defines the cardinality of a relationship attribute.
+type RelationType string
+
+const (
+	RelationOneToOne   RelationType = "oneToOne"
+	RelationOneToMany  RelationType = "oneToMany"
+	RelationManyToOne  RelationType = "manyToOne"
+	RelationManyToMany RelationType = "manyToMany"
+)
+
+// OnDelete defines what happens to related documents when a document is deleted.
+type OnDelete string
+
+const (
+	OnDeleteCascade  OnDelete = "cascade"
+	OnDeleteRestrict OnDelete = "restrict"
+	OnDeleteSetNull  OnDelete = "setNull"
+)
+
+// Base returns a.
+func (a *Attribute) Base() *Attribute {
+	return a
+}
+
+// Validate checks the fields shared by all attribute types.
+func (a *Attribute) Validate() error {
+	if a.Key == "" {
+		return fmt.Errorf("gowrite: attribute key is required")
+	}
+	return nil
+}
+
+func (a *Attribute) createRequest() (string, map[string]interface{}) {
+	return a.Type, a.payload(nil)
+}
+
+// payload returns the common create fields with def as the default value.
+func (a *Attribute) payload(def interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"key":      a.Key,
+		"required": a.Required,
+		"array":    a.Array,
+		"default":  def,
+	}
+}
+
+// validateDefault rejects defaults on required and array attributes, which the server
+// does not accept.
+func (a *Attribute) validateDefault(hasDefault bool) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	if hasDefault && a.Required {
+		return fmt.Errorf("gowrite: attribute %q: required attributes cannot have a default", a.Key)
+	}
+	if hasDefault && a.Array {
+		return fmt.Errorf("gowrite: attribute %q: array attributes cannot have a default", a.Key)
+	}
+	return nil
+}
+
+// StringAttribute is a string attribute of at most Size characters.
+type StringAttribute struct {
+	Attribute
+	Size    int     `json:"size"`
+	Default *string `json:"default"`
+	Encrypt bool    `json:"encrypt"`
+}
+
+// Validate checks the size and default of the attribute.
+func (a *StringAttribute) Validate() error {
+	if err := a.validateDefault(a.Default != nil); err != nil {
+		return err
+	}
+	if a.Size <= 0 {
+		return fmt.Errorf("gowrite: attribute %q: size must be positive", a.Key)
+	}
+	if a.Default != nil && len([]rune(*a.Default)) > a.Size {
+		return fmt.Errorf("gowrite: attribute %q: default is longer than size %d", a.Key, a.Size)
+	}
+	return nil
+}
+
+func (a *StringAttribute) createRequest() (string, map[string]interface{}) {
+	p := a.payload(a.Default)
+	p["size"] = a.Size
+	if a.Encrypt {
+		p["encrypt"] = true
+	}
+	return string(AttributeString), p
+}
+
+// IntegerAttribute is an integer attribute within the optional Min and Max bounds.
+type IntegerAttribute struct {
+	Attribute
+	Min     *int64 `json:"min"`
+	Max     *int64 `json:"max"`
+	Default *int64 `json:"default"`
+}
+
+// Validate checks the bounds and default of the attribute.
+func (a *IntegerAttribute) Validate() error {
+	if err := a.validateDefault(a.Default != nil); err != nil {
+		return err
+	}
+	if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+		return fmt.Errorf("gowrite: attribute %q: min is greater than max", a.Key)
+	}
+	if a.Default != nil && ((a.Min != nil && *a.Default < *a.Min) || (a.Max != nil && *a.Default > *a.Max)) {
+		return fmt.Errorf("gowrite: attribute %q: default is out of range", a.Key)
+	}
+	return nil
+}
+
+func (a *IntegerAttribute) createRequest() (string, map[string]interface{}) {
+	p := a.payload(a.Default)
+	if a.Min != nil {
+		p["min"] = *a.Min
+	}
+	if a.Max != nil {
+		p["max"] = *a.Max
+	}
+	return string(AttributeInteger), p
+}
+
+// FloatAttribute is a floating point attribute within the optional Min and Max bounds.
+type FloatAttribute struct {
+	Attribute
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	Default *float64 `json:"default"`
+}
+
+// Validate checks the bounds and default of the attribute.
+func (a *FloatAttribute) Validate() error {
+	if err := a.validateDefault(a.Default != nil); err != nil {
+		return err
+	}
+	if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+		return fmt.Errorf("gowrite: attribute %q: min is greater than max", a.Key)
+	}
+	if a.Default != nil && ((a.Min != nil && *a.Default < *a.Min) || (a.Max != nil && *a.Default > *a.Max)) {
+		return fmt.Errorf("gowrite: attribute %q: default is out of range", a.Key)
+	}
+	return nil
+}
+
+func (a *FloatAttribute) createRequest() (string, map[string]interface{}) {
+	p := a.payload(a.Default)
+	if a.Min != nil {
+		p["min"] = *a.Min
+	}
+	if a.Max != nil {
+		p["max"] = *a.Max
+	}
+	return string(AttributeFloat), p
+}
+
+// BooleanAttribute is a boolean attribute.
+type BooleanAttribute struct {
+	Attribute
+	Default *bool `json:"default"`
+}
+
+// Validate checks the default of the attribute.
+func (a *BooleanAttribute) Validate() error {
+	return a.validateDefault(a.Default != nil)
+}
+
+func (a *BooleanAttribute) createRequest() (string, map[string]interface{}) {
+	return string(AttributeBoolean), a.payload(a.Default)
+}
+
+// DatetimeAttribute is a datetime attribute; Default is an ISO 8601 timestamp.
+type DatetimeAttribute struct {
+	Attribute
+	Default *string `json:"default"`
+}
+
+// Validate checks the default of the attribute.
+func (a *DatetimeAttribute) Validate() error {
+	if err := a.validateDefault(a.Default != nil); err != nil {
+		return err
+	}
+	if a.Default != nil {
+		if _, err := parseTimestamp(*a.Default); err != nil {
+			return fmt.Errorf("gowrite: attribute %q: invalid default: %w", a.Key, err)
+		}
+	}
+	return nil
+}
+
+func (a *DatetimeAttribute) createRequest() (string, map[string]interface{}) {
+	return string(AttributeDatetime), a.payload(a.Default)
+}
+
+// EmailAttribute is a string attribute holding an email address.
+type EmailAttribute struct {
+	Attribute
+	Default *string `json:"default"`
+}
+
+// Validate checks the default of the attribute.
+func (a *EmailAttribute) Validate() error {
+	return a.validateDefault(a.Default != nil)
+}
+
+func (a *EmailAttribute) createRequest() (string, map[string]interface{}) {
+	return string(AttributeEmail), a.payload(a.Default)
+}
+
+// IPAttribute is a string attribute holding an IP address.
+type IPAttribute struct {
+	Attribute
+	Default *string `json:"default"`
+}
+
+// Validate checks the default of the attribute.
+func (a *IPAttribute) Validate() error {
+	return a.validateDefault(a.Default != nil)
+}
+
+func (a *IPAttribute) createRequest() (string, map[string]interface{}) {
+	return string(AttributeIP), a.payload(a.Default)
+}
+
+// URLAttribute is a string attribute holding a URL.
+type URLAttribute struct {
+	Attribute
+	Default *string `json:"default"`
+}
+
+// Validate checks the default of the attribute.
+func (a *URLAttribute) Validate() error {
+	return a.validateDefault(a.Default != nil)
+}
+
+func (a *URLAttribute) createRequest() (string, map[string]interface{}) {
+	return string(AttributeURL), a.payload(a.Default)
+}
+
+// EnumAttribute is a string attribute restricted to Elements.
+type EnumAttribute struct {
+	Attribute
+	Elements []string `json:"elements"`
+	Default  *string  `json:"default"`
+}
+
+// Validate checks the elements and default of the attribute.
+func (a *EnumAttribute) Validate() error {
+	if err := a.validateDefault(a.Default != nil); err != nil {
+		return err
+	}
+	if len(a.Elements) == 0 {
+		return fmt.Errorf("gowrite: attribute %q: enum needs at least one element", a.Key)
+	}
+	if a.Default != nil && !slices.Contains(a.Elements, *a.Default) {
+		return fmt.Errorf("gowrite: attribute %q: default %q is not an element", a.Key, *a.Default)
+	}
+	return nil
+}
+
+func (a *EnumAttribute) createRequest() (string, map[string]interface{}) {
+	p := a.payload(a.Default)
+	p["elements"] = a.Elements
+	return string(AttributeEnum), p
+}
+
+// RelationshipAttribute links documents of the collection to RelatedCollection. Type
+// is the relation type; the attribute type itself is in Attribute.Type.
+type RelationshipAttribute struct {
+	Attribute
+	RelatedCollection string       `json:"relatedCollection"`
+	Type              RelationType `json:"relationType"`
+	TwoWay            bool         `json:"twoWay"`
+	TwoWayKey         string       `json:"twoWayKey"`
+	OnDelete          OnDelete     `json:"onDelete"`
+	// Side is "parent" or "child", as reported by the server.
+	Side string `json:"side"`
+}
+
+// Validate checks the related collection, relation type and delete behaviour.
+func (a *RelationshipAttribute) Validate() error {
+	if err := a.Attribute.Validate(); err != nil {
+		return err
+	}
+	if a.RelatedCollection == "" {
+		return fmt.Errorf("gowrite: attribute %q: related collection is required", a.Key)
+	}
+	switch a.Type {
+	case RelationOneToOne, RelationOneToMany, RelationManyToOne, RelationManyToMany:
+	default:
+		return fmt.Errorf("gowrite: attribute %q: invalid relation type %q", a.Key, a.Type)
+	}
+	switch a.OnDelete {
+	case "", OnDeleteCascade, OnDeleteRestrict, OnDeleteSetNull:
+	default:
+		return fmt.Errorf("gowrite: attribute %q: invalid onDelete %q", a.Key, a.OnDelete)
+	}
+	return nil
+}
+
+func (a *RelationshipAttribute) createRequest() (string, map[string]interface{}) {
+	p := map[string]interface{}{
+		"relatedCollectionId": a.RelatedCollection,
+		"type":                a.Type,
+		"twoWay":              a.TwoWay,
+		"key":                 a.Key,
+	}
+	if a.TwoWayKey != "" {
+		p["twoWayKey"] = a.TwoWayKey
+	}
+	if a.OnDelete != "" {
+		p["onDelete"] = a.OnDelete
+	}
+	return string(AttributeRelationship), p
+}
+
+// AttributeList decodes a list of attributes into their typed models.
+type AttributeList []TypedAttribute
+
+// UnmarshalJSON decodes each attribute according to its type and format.
+func (l *AttributeList) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	out := make(AttributeList, 0, len(raw))
+	for _, r := range raw {
+		attr, err := decodeAttribute(r)
+		if err != nil {
+			return err
+		}
+		out = append(out, attr)
+	}
+	*l = out
+	return nil
+}
+
+// TypedAttributes decodes the attributes of the collection into their typed models.
+func (c *Collection) TypedAttributes() (AttributeList, error) {
+	b, err := json.Marshal(c.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	var attrs AttributeList
+	if err := json.Unmarshal(b, &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// decodeAttribute decodes an attribute into the model matching its type and format.
+func decodeAttribute(b []byte) (TypedAttribute, error) {
+	var head struct {
+		Type   string `json:"type"`
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+
+	var attr TypedAttribute
+	switch head.Type {
+	case "string":
+		switch head.Format {
+		case "email":
+			attr = &EmailAttribute{}
+		case "ip":
+			attr = &IPAttribute{}
+		case "url":
+			attr = &URLAttribute{}
+		case "enum":
+			attr = &EnumAttribute{}
+		default:
+			attr = &StringAttribute{}
+		}
+	case "integer":
+		attr = &IntegerAttribute{}
+	case "double", "float":
+		attr = &FloatAttribute{}
+	case "boolean":
+		attr = &BooleanAttribute{}
+	case "datetime":
+		attr = &DatetimeAttribute{}
+	case "relationship":
+		attr = &RelationshipAttribute{}
+	default:
+		attr = &Attribute{}
+	}
+	if err := json.Unmarshal(b, attr); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
+
+// GetTypedAttribute retrieves an attribute from a collection, decoded into the model
+// of its type such as *StringAttribute.
+func (db *DatabaseService) GetTypedAttribute(databaseID, collectionID, key string) (TypedAttribute, error) {
+	return db.GetTypedAttributeCtx(context.Background(), databaseID, collectionID, key)
+}
+
+// GetTypedAttributeCtx is like GetTypedAttribute but uses ctx for the request.
+func (db *DatabaseService) GetTypedAttributeCtx(ctx context.Context, databaseID, collectionID, key string) (TypedAttribute, error) {
+	respBody, err := db.getAttribute(ctx, databaseID, collectionID, key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAttribute(respBody)
+}
+
+// ListTypedAttributes retrieves all attributes from a collection, decoded into the
+// models of their types.
+func (db *DatabaseService) ListTypedAttributes(databaseID, collectionID string, queries []string) (AttributeList, error) {
+	return db.ListTypedAttributesCtx(context.Background(), databaseID, collectionID, queries)
+}
+
+// ListTypedAttributesCtx is like ListTypedAttributes but uses ctx for the request.
+func (db *DatabaseService) ListTypedAttributesCtx(ctx context.Context, databaseID, collectionID string, queries []string) (AttributeList, error) {
+	respBody, err := db.listAttributes(ctx, databaseID, collectionID, queries)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Attributes AttributeList `json:"attributes"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	return result.Attributes, nil
+}
+
+// CreateTypedAttribute validates attr and creates it in a collection. It returns the
+// attribute as stored by the server.
+func (db *DatabaseService) CreateTypedAttribute(databaseID, collectionID string, attr TypedAttribute) (TypedAttribute, error) {
+	return db.CreateTypedAttributeCtx(context.Background(), databaseID, collectionID, attr)
+}
+
+// CreateTypedAttributeCtx is like CreateTypedAttribute but uses ctx for the request.
+func (db *DatabaseService) CreateTypedAttributeCtx(ctx context.Context, databaseID, collectionID string, attr TypedAttribute) (TypedAttribute, error) {
+	if err := attr.Validate(); err != nil {
+		return nil, err
+	}
+	attrType, payload := attr.createRequest()
+
+	path := fmt.Sprintf("/databases/%s/collections/%s/attributes/%s", databaseID, collectionID, attrType)
+	op := newOperation("databases", "createAttribute", "databaseId", databaseID, "collectionId", collectionID, "key", attr.Base().Key)
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", path, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeAttribute(respBody)
+}
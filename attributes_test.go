@@ -0,0 +1,116 @@
+package gowrite_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+)
+
+func TestAttributeListDecoding(t *testing.T) {
+	raw := `{"$id":"c","attributes":[
+		{"key":"title","type":"string","status":"available","required":true,"array":false,"size":128,"default":null},
+		{"key":"views","type":"integer","required":false,"min":0,"max":1000,"default":5},
+		{"key":"mail","type":"string","format":"email","required":false,"default":"a@b.c"},
+		{"key":"state","type":"string","format":"enum","elements":["draft","live"],"default":"draft"},
+		{"key":"author","type":"relationship","relatedCollection":"users","relationType":"manyToOne","twoWay":true,"twoWayKey":"posts","onDelete":"setNull","side":"parent"},
+		{"key":"geo","type":"point"}
+	]}`
+
+	var col gowrite.Collection
+	if err := json.Unmarshal([]byte(raw), &col); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	attrs, err := col.TypedAttributes()
+	if err != nil || len(attrs) != 6 {
+		t.Fatalf("TypedAttributes = %d attributes, %v", len(attrs), err)
+	}
+	if s, ok := attrs[0].(*gowrite.StringAttribute); !ok || s.Size != 128 || !s.Required {
+		t.Errorf("attributes[0] = %#v", attrs[0])
+	}
+	if i, ok := attrs[1].(*gowrite.IntegerAttribute); !ok || *i.Max != 1000 || *i.Default != 5 {
+		t.Errorf("attributes[1] = %#v", attrs[1])
+	}
+	if _, ok := attrs[2].(*gowrite.EmailAttribute); !ok {
+		t.Errorf("attributes[2] = %#v", attrs[2])
+	}
+	if e, ok := attrs[3].(*gowrite.EnumAttribute); !ok || len(e.Elements) != 2 {
+		t.Errorf("attributes[3] = %#v", attrs[3])
+	}
+	r, ok := attrs[4].(*gowrite.RelationshipAttribute)
+	if !ok || r.Type != gowrite.RelationManyToOne || r.Attribute.Type != "relationship" || r.OnDelete != gowrite.OnDeleteSetNull {
+		t.Errorf("attributes[4] = %#v", attrs[4])
+	}
+	if a := attrs[5].Base(); a.Key != "geo" || a.Type != "point" {
+		t.Errorf("attributes[5] = %#v", attrs[5])
+	}
+}
+
+func TestCreateTypedAttribute(t *testing.T) {
+	var (
+		path string
+		body map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"key":"state","type":"string","format":"enum","elements":["draft","live"],"status":"processing"}`)
+	}))
+	defer srv.Close()
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+
+	def := "gone"
+	invalid := &gowrite.EnumAttribute{Attribute: gowrite.Attribute{Key: "state"}, Elements: []string{"draft", "live"}, Default: &def}
+	if _, err := db.CreateTypedAttribute("db", "c", invalid); err == nil || path != "" {
+		t.Fatalf("invalid attribute was sent: err = %v", err)
+	}
+
+	def = "draft"
+	attr, err := db.CreateTypedAttribute("db", "c", invalid)
+	if err != nil {
+		t.Fatalf("CreateTypedAttribute: %v", err)
+	}
+	if path != "/v1/databases/db/collections/c/attributes/enum" || body["default"] != "draft" || len(body["elements"].([]interface{})) != 2 {
+		t.Fatalf("request = %s %v", path, body)
+	}
+	if _, ok := attr.(*gowrite.EnumAttribute); !ok {
+		t.Fatalf("attr = %#v", attr)
+	}
+}
+
+func TestGetTypedAttribute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/databases/db/collections/c/attributes/views":
+			fmt.Fprint(w, `{"key":"views","type":"integer","status":"available","min":0,"max":10}`)
+		case "/v1/databases/db/collections/c/attributes":
+			fmt.Fprint(w, `{"total":1,"attributes":[{"key":"views","type":"integer","status":"available","min":0,"max":10}]}`)
+		}
+	}))
+	defer srv.Close()
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+
+	attr, err := db.GetAttribute("db", "c", "views")
+	if err != nil || attr.Key != "views" || attr.Type != "integer" {
+		t.Fatalf("GetAttribute = %+v, %v", attr, err)
+	}
+	typed, err := db.GetTypedAttribute("db", "c", "views")
+	if i, ok := typed.(*gowrite.IntegerAttribute); err != nil || !ok || *i.Max != 10 {
+		t.Fatalf("GetTypedAttribute = %#v, %v", typed, err)
+	}
+
+	list, err := db.ListAttributes("db", "c", nil)
+	if err != nil || len(list) != 1 || list[0].Key != "views" {
+		t.Fatalf("ListAttributes = %v, %v", list, err)
+	}
+	typedList, err := db.ListTypedAttributes("db", "c", nil)
+	if err != nil || len(typedList) != 1 {
+		t.Fatalf("ListTypedAttributes = %v, %v", typedList, err)
+	}
+	if _, ok := typedList[0].(*gowrite.IntegerAttribute); !ok {
+		t.Fatalf("ListTypedAttributes[0] = %#v", typedList[0])
+	}
+}
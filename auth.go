@@ -0,0 +1,84 @@
+package gowrite
+
+import (
+	"context"
+	"net/http"
+)
+
+// Credentials authenticate the requests sent by a client.
+type Credentials interface {
+	Apply(h http.Header)
+}
+
+// APIKey authenticates as the server with a project API key.
+type APIKey string
+
+// Apply sets the X-Appwrite-Key header.
+func (k APIKey) Apply(h http.Header) {
+	h.Set("X-Appwrite-Key", string(k))
+}
+
+// JWT acts on behalf of an end user with a JWT created by the user's session, so that
+// the server enforces the user's permissions.
+type JWT string
+
+// Apply sets the X-Appwrite-JWT header.
+func (t JWT) Apply(h http.Header) {
+	h.Set("X-Appwrite-JWT", string(t))
+}
+
+// Session acts on behalf of an end user with a session secret.
+type Session string
+
+// Apply sets the X-Appwrite-Session header.
+func (s Session) Apply(h http.Header) {
+	h.Set("X-Appwrite-Session", string(s))
+}
+
+// Anonymous sends requests without credentials, with the permissions of a guest.
+type Anonymous struct{}
+
+// Apply leaves the headers untouched.
+func (Anonymous) Apply(http.Header) {}
+
+type credentialsKey struct{}
+
+// ContextWithCredentials returns a context whose requests use c instead of the
+// client's credentials.
+func ContextWithCredentials(ctx context.Context, c Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, c)
+}
+
+// credentials picks the per-call credentials from ctx, then the client's Credentials,
+// then its APIKey.
+func (client *AppwriteClient) credentials(ctx context.Context) Credentials {
+	if c, ok := ctx.Value(credentialsKey{}).(Credentials); ok && c != nil {
+		return c
+	}
+	if client.Credentials != nil {
+		return client.Credentials
+	}
+	return APIKey(client.APIKey)
+}
+
+// WithCredentials returns a copy of the client authenticating with c. Unlike WithRetry
+// and friends it does not modify the receiver; the copy shares the HTTP client, retry
+// policy, rate limiter, observer and cached server version, so deriving a client per
+// end user is cheap. The copy starts with the receiver's middlewares, but middlewares
+// added later with Use only apply to the client they were added to.
+func (client *AppwriteClient) WithCredentials(c Credentials) *AppwriteClient {
+	derived := *client
+	derived.Credentials = c
+	derived.middlewares = append([]Middleware(nil), client.middlewares...)
+	return &derived
+}
+
+// WithJWT returns a copy of the client acting on behalf of the user owning token.
+func (client *AppwriteClient) WithJWT(token string) *AppwriteClient {
+	return client.WithCredentials(JWT(token))
+}
+
+// WithSession returns a copy of the client acting on behalf of the session secret.
+func (client *AppwriteClient) WithSession(secret string) *AppwriteClient {
+	return client.WithCredentials(Session(secret))
+}
@@ -0,0 +1,230 @@
+// Package backup snapshots an Appwrite database, and optionally storage buckets,
+// into a portable archive and restores it into the same or another project.
+//
+// The archive is a gzip compressed tar file:
+//
+//	manifest.json                 format version, database schema, buckets and files
+//	documents/<collection>.ndjson documents as written by ExportCollection
+//	files/<bucket>/<file>         file contents
+//
+// Restore recreates the schema, imports the documents without their relationships,
+// links the relationships once every document exists and uploads the files.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/schema"
+)
+
+// FormatVersion is the version of the archive layout written by Create.
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Database holds the database with its collections, attributes and indexes.
+	Database    schema.Database `json:"database"`
+	Collections []Collection    `json:"collections"`
+	Buckets     []Bucket        `json:"buckets,omitempty"`
+}
+
+// Collection describes the documents of a collection in the archive.
+type Collection struct {
+	ID        string `json:"id"`
+	Documents int    `json:"documents"`
+	// Relationships lists the relationship attributes of the collection, including
+	// the child sides of two-way relationships; Restore sets them in a second pass.
+	Relationships []string `json:"relationships,omitempty"`
+}
+
+// Bucket describes a storage bucket and its files in the archive.
+type Bucket struct {
+	Bucket *gowrite.Bucket `json:"bucket"`
+	Files  []File          `json:"files"`
+}
+
+// File describes a stored file.
+type File struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	MimeType    string   `json:"mimeType,omitempty"`
+	Size        int64    `json:"size"`
+	Permissions []string `json:"permissions"`
+}
+
+// Options controls Create.
+type Options struct {
+	// Buckets lists storage buckets to include with all of their files; they are
+	// read through Storage.
+	Buckets []string
+	Storage *gowrite.StorageService
+}
+
+func documentsEntry(collectionID string) string {
+	return path.Join("documents", collectionID+".ndjson")
+}
+
+func fileEntry(bucketID, fileID string) string {
+	return path.Join("files", bucketID, fileID)
+}
+
+// Create writes an archive of the database, its collections and documents, and the
+// buckets in opts to w. The content is staged in a temporary directory first so the
+// manifest can lead the archive.
+func Create(ctx context.Context, db *gowrite.DatabaseService, databaseID string, w io.Writer, opts Options) (*Manifest, error) {
+	if len(opts.Buckets) > 0 && opts.Storage == nil {
+		return nil, errors.New("backup: buckets given without a storage service")
+	}
+
+	s, err := schema.Inspect(ctx, db, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC(), Database: s.Databases[0]}
+
+	dir, err := os.MkdirTemp("", "gowrite-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	relationships := relationshipKeys(m.Database)
+	var entries []string
+	for _, c := range m.Database.Collections {
+		name := documentsEntry(c.ID)
+		var n int
+		err := writeStaged(dir, name, func(w io.Writer) (err error) {
+			n, err = db.ExportCollectionCtx(ctx, databaseID, c.ID, w, gowrite.FormatNDJSON, nil)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("backup: collection %s: %w", c.ID, err)
+		}
+		entries = append(entries, name)
+		m.Collections = append(m.Collections, Collection{ID: c.ID, Documents: n, Relationships: relationships[c.ID]})
+	}
+
+	for _, bucketID := range opts.Buckets {
+		bucket, err := opts.Storage.GetBucketCtx(ctx, bucketID)
+		if err != nil {
+			return nil, fmt.Errorf("backup: bucket %s: %w", bucketID, err)
+		}
+		mb := Bucket{Bucket: bucket, Files: []File{}}
+		for f, err := range opts.Storage.IterateFiles(ctx, bucketID) {
+			if err != nil {
+				return nil, fmt.Errorf("backup: bucket %s: %w", bucketID, err)
+			}
+			name := fileEntry(bucketID, f.ID)
+			var size int64
+			if err := writeStaged(dir, name, func(w io.Writer) (err error) {
+				size, err = opts.Storage.DownloadFileToCtx(ctx, bucketID, f.ID, w)
+				return err
+			}); err != nil {
+				return nil, fmt.Errorf("backup: file %s/%s: %w", bucketID, f.ID, err)
+			}
+			entries = append(entries, name)
+			mb.Files = append(mb.Files, File{ID: f.ID, Name: f.Name, MimeType: f.MimeType, Size: size, Permissions: f.Permissions})
+		}
+		m.Buckets = append(m.Buckets, mb)
+	}
+
+	return m, writeArchive(w, m, dir, entries)
+}
+
+// relationshipKeys returns the relationship attributes per collection. The schema
+// read from the server holds both sides of two-way relationships.
+func relationshipKeys(d schema.Database) map[string][]string {
+	keys := make(map[string][]string)
+	for _, c := range d.Collections {
+		for _, a := range c.Attributes {
+			if a.Type == string(gowrite.AttributeRelationship) {
+				keys[c.ID] = append(keys[c.ID], a.Key)
+			}
+		}
+	}
+	return keys
+}
+
+// writeStaged creates the file name below dir and fills it with write.
+func writeStaged(dir, name string, write func(io.Writer) error) error {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeArchive writes the manifest followed by the staged entries.
+func writeArchive(w io.Writer, m *Manifest, dir string, entries []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: manifestName, Mode: 0o644, Size: int64(len(manifest)), ModTime: m.CreatedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, name := range entries {
+		if err := addFile(tw, filepath.Join(dir, filepath.FromSlash(name)), name, m.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, src, name string, modTime time.Time) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,209 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/backup"
+)
+
+const (
+	usersJSON = `{"$id":"users","name":"Users","enabled":true,"attributes":[` +
+		`{"key":"name","type":"string","size":64,"status":"available"},` +
+		`{"key":"posts","type":"relationship","relatedCollection":"posts","relationType":"manyToOne","twoWay":true,"twoWayKey":"author","side":"child","status":"available"}],"indexes":[]}`
+	postsJSON = `{"$id":"posts","name":"Posts","enabled":true,"attributes":[` +
+		`{"key":"title","type":"string","size":255,"status":"available"},` +
+		`{"key":"author","type":"relationship","relatedCollection":"users","relationType":"manyToOne","twoWay":true,"twoWayKey":"posts","side":"parent","status":"available"}],"indexes":[]}`
+)
+
+// sourceProject serves a blog database whose posts belong to users, and a media bucket
+// with one file.
+func sourceProject(t *testing.T) *gowrite.AppwriteClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.4"}`)
+		case "/v1/databases/blog":
+			fmt.Fprint(w, `{"$id":"blog","name":"Blog","enabled":true}`)
+		case "/v1/databases/blog/collections":
+			fmt.Fprintf(w, `{"total":2,"collections":[%s,%s]}`, usersJSON, postsJSON)
+		case "/v1/databases/blog/collections/users":
+			fmt.Fprint(w, usersJSON)
+		case "/v1/databases/blog/collections/posts":
+			fmt.Fprint(w, postsJSON)
+		case "/v1/databases/blog/collections/users/documents":
+			fmt.Fprint(w, `{"total":1,"documents":[{"$id":"u1","$permissions":[],"name":"Ann","posts":[{"$id":"p1","title":"Hi"}]}]}`)
+		case "/v1/databases/blog/collections/posts/documents":
+			fmt.Fprint(w, `{"total":1,"documents":[{"$id":"p1","$permissions":["read(\"any\")"],"title":"Hi","author":{"$id":"u1","name":"Ann"}}]}`)
+		case "/v1/storage/buckets/media":
+			fmt.Fprint(w, `{"$id":"media","name":"Media","enabled":true,"maximumFileSize":1024}`)
+		case "/v1/storage/buckets/media/files":
+			fmt.Fprint(w, `{"total":1,"files":[{"$id":"f1","bucketId":"media","name":"a.txt","mimeType":"text/plain","$permissions":[]}]}`)
+		case "/v1/storage/buckets/media/files/f1/download":
+			fmt.Fprint(w, "hello")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found","code":404,"type":"general_not_found"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return gowrite.NewClient(srv.URL, "p", "k")
+}
+
+// emptyProject accepts every write and records it as "METHOD path body".
+func emptyProject(t *testing.T) (*gowrite.AppwriteClient, func() []string) {
+	var (
+		mu     sync.Mutex
+		writes []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1")
+		switch {
+		case path == "/health/version":
+			fmt.Fprint(w, `{"version":"1.7.4"}`)
+		case r.Method == "GET" && strings.HasPrefix(path, "/databases/copy/collections/"):
+			fmt.Fprint(w, `{"attributes":[],"indexes":[]}`)
+		case r.Method == "GET":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found","code":404,"type":"database_not_found"}`)
+		default:
+			var body string
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+				r.ParseMultipartForm(1 << 20)
+				f, _, _ := r.FormFile("file")
+				content, _ := io.ReadAll(f)
+				body = r.FormValue("fileId") + "=" + string(content)
+			} else {
+				var v struct {
+					Data              map[string]interface{} `json:"data"`
+					RelatedCollection string                 `json:"relatedCollectionId"`
+				}
+				json.NewDecoder(r.Body).Decode(&v)
+				if v.Data != nil {
+					b, _ := json.Marshal(v.Data)
+					body = string(b)
+				} else if v.RelatedCollection != "" {
+					body = "related=" + v.RelatedCollection
+				}
+			}
+			mu.Lock()
+			writes = append(writes, strings.TrimSpace(r.Method+" "+path+" "+body))
+			mu.Unlock()
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return gowrite.NewClient(srv.URL, "p", "k"), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), writes...)
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	ctx := context.Background()
+	src := sourceProject(t)
+
+	var archive bytes.Buffer
+	m, err := backup.Create(ctx, gowrite.NewDatabases(src), "blog", &archive, backup.Options{
+		Buckets: []string{"media"},
+		Storage: gowrite.NewStorage(src),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(m.Collections) != 2 || m.Collections[0].Documents != 1 || fmt.Sprint(m.Collections[0].Relationships) != "[posts]" {
+		t.Fatalf("manifest collections = %+v", m.Collections)
+	}
+	if len(m.Buckets) != 1 || len(m.Buckets[0].Files) != 1 || m.Buckets[0].Files[0].Size != 5 {
+		t.Fatalf("manifest buckets = %+v", m.Buckets)
+	}
+
+	dst, writes := emptyProject(t)
+	res, err := backup.Restore(ctx, gowrite.NewDatabases(dst), &archive, backup.RestoreOptions{
+		DatabaseID:    "copy",
+		CollectionIDs: map[string]string{"users": "members"},
+		Storage:       gowrite.NewStorage(dst),
+		BucketIDs:     map[string]string{"media": "media2"},
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := res.Err(); err != nil || res.Files != 1 || res.Collections["posts"].Imported != 1 {
+		t.Fatalf("Restore result = %+v, %v", res, err)
+	}
+
+	got := writes()
+	index := func(want string) int {
+		for i, w := range got {
+			if w == want {
+				return i
+			}
+		}
+		t.Fatalf("missing write %q in:\n%s", want, strings.Join(got, "\n"))
+		return -1
+	}
+	index("POST /databases")
+	index("POST /databases/copy/collections/posts/attributes/relationship related=members")
+	index("POST /storage/buckets/media2/files f1=hello")
+	putPost := index(`PUT /databases/copy/collections/posts/documents/p1 {"title":"Hi"}`)
+	putUser := index(`PUT /databases/copy/collections/members/documents/u1 {"name":"Ann"}`)
+	linkPost := index(`PATCH /databases/copy/collections/posts/documents/p1 {"author":"u1"}`)
+	linkUser := index(`PATCH /databases/copy/collections/members/documents/u1 {"posts":["p1"]}`)
+	if max(putPost, putUser) > min(linkPost, linkUser) {
+		t.Fatalf("relationships linked before every document existed:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestRestoreRejectsUnsafeCollectionID(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	manifest := `{"version":1,"database":{"id":"blog"},"collections":[{"id":"../../escaped","documents":1}]}`
+	tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0o644, Size: int64(len(manifest))})
+	tw.Write([]byte(manifest))
+	tw.Close()
+	gz.Close()
+
+	dst, writes := emptyProject(t)
+	_, err := backup.Restore(context.Background(), gowrite.NewDatabases(dst), &archive, backup.RestoreOptions{})
+	if err == nil || !strings.Contains(err.Error(), "invalid collection id") {
+		t.Fatalf("Restore = %v, want an invalid collection id error", err)
+	}
+	if got := writes(); len(got) != 0 {
+		t.Fatalf("writes after a rejected manifest: %v", got)
+	}
+}
+
+func TestRestoreErrAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	src := sourceProject(t)
+	var archive bytes.Buffer
+	if _, err := backup.Create(ctx, gowrite.NewDatabases(src), "blog", &archive, backup.Options{}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"message":"unavailable","code":500,"type":"general_unknown"}`)
+	}))
+	t.Cleanup(srv.Close)
+	res, err := backup.Restore(ctx, gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")), &archive, backup.RestoreOptions{})
+	if err == nil || res == nil {
+		t.Fatalf("Restore = %+v, %v, want a failure with a result", res, err)
+	}
+	if err := res.Err(); err != nil {
+		t.Fatalf("Err of a restore that imported nothing = %v", err)
+	}
+}
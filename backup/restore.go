@@ -0,0 +1,390 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/schema"
+)
+
+// defaultConcurrency is the number of documents written in parallel by default.
+const defaultConcurrency = 5
+
+// validID matches the IDs Appwrite accepts. Collection IDs name staged files, so the
+// manifest is rejected when one could escape the staging directory.
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,35}$`)
+
+// RestoreOptions controls Restore.
+type RestoreOptions struct {
+	// DatabaseID restores the database under a new ID; empty keeps the original.
+	DatabaseID string
+	// Storage restores the archived buckets and files; without it they are skipped.
+	Storage *gowrite.StorageService
+	// CollectionIDs maps archived collection IDs to the IDs to restore them under,
+	// including the related collection of relationship attributes. Collections
+	// without an entry keep their ID.
+	CollectionIDs map[string]string
+	// BucketIDs maps archived bucket IDs to the IDs to restore them under. Buckets
+	// without an entry keep their ID.
+	BucketIDs map[string]string
+	// Concurrency is the number of documents written in parallel; 0 means 5.
+	Concurrency int
+}
+
+// Result reports the outcome of Restore.
+type Result struct {
+	Manifest *Manifest
+	// Collections holds the import summary of each collection by its archived ID.
+	// Documents whose relationships could not be linked are listed among the failed
+	// rows.
+	Collections map[string]*gowrite.ImportSummary
+	// Files is the number of files uploaded.
+	Files int
+	// FileErrors lists the files that could not be uploaded.
+	FileErrors []error
+}
+
+// Err returns the document and file errors joined, or nil when everything was
+// restored. Collections that were not reached before Restore failed are left out.
+func (r *Result) Err() error {
+	var errs []error
+	for _, c := range r.Manifest.Collections {
+		summary, ok := r.Collections[c.ID]
+		if !ok {
+			continue
+		}
+		if err := summary.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("collection %s: %w", c.ID, err))
+		}
+	}
+	return errors.Join(append(errs, r.FileErrors...)...)
+}
+
+// Restore recreates an archive written by Create. The schema is applied first, then
+// the documents are imported without their relationships, which are linked in a
+// second pass so that collections can reference each other in any order. Existing
+// documents and files with the same IDs are replaced and kept respectively, so an
+// interrupted restore can be run again. The database, collections and buckets can be
+// restored under new IDs; document and file IDs are kept.
+//
+// Document and file failures are reported in the result; the returned error is set
+// when the archive cannot be read or the schema or buckets cannot be created.
+func Restore(ctx context.Context, db *gowrite.DatabaseService, r io.Reader, opts RestoreOptions) (*Result, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	m, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Manifest: m, Collections: make(map[string]*gowrite.ImportSummary)}
+
+	databaseID := m.Database.ID
+	if opts.DatabaseID != "" {
+		databaseID = opts.DatabaseID
+	}
+	target := m.Database
+	target.ID = databaseID
+	target.Collections = make([]schema.Collection, len(m.Database.Collections))
+	for i, c := range m.Database.Collections {
+		c.ID = opts.collectionID(c.ID)
+		c.Attributes = slices.Clone(c.Attributes)
+		for j, a := range c.Attributes {
+			if a.RelatedCollection != "" {
+				c.Attributes[j].RelatedCollection = opts.collectionID(a.RelatedCollection)
+			}
+		}
+		target.Collections[i] = c
+	}
+	s := &schema.Schema{Databases: []schema.Database{target}}
+	plan, err := s.Plan(ctx, db, schema.Options{})
+	if err != nil {
+		return res, err
+	}
+	if err := plan.Apply(ctx, db); err != nil {
+		return res, err
+	}
+
+	type restoredFile struct {
+		bucketID string
+		File
+	}
+	files := make(map[string]restoredFile)
+	if opts.Storage != nil {
+		for _, b := range m.Buckets {
+			bucketID := opts.bucketID(b.Bucket.ID)
+			if err := createBucket(ctx, opts.Storage, bucketID, b.Bucket); err != nil {
+				return res, fmt.Errorf("backup: bucket %s: %w", bucketID, err)
+			}
+			for _, f := range b.Files {
+				files[fileEntry(b.Bucket.ID, f.ID)] = restoredFile{bucketID, f}
+			}
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "gowrite-restore-*")
+	if err != nil {
+		return res, err
+	}
+	defer os.RemoveAll(dir)
+
+	collections := make(map[string]Collection, len(m.Collections))
+	for _, c := range m.Collections {
+		collections[documentsEntry(c.ID)] = c
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("backup: %w", err)
+		}
+
+		if c, ok := collections[hdr.Name]; ok {
+			if err := splitRelationships(tr, dir, c); err != nil {
+				return res, fmt.Errorf("backup: collection %s: %w", c.ID, err)
+			}
+			continue
+		}
+		f, ok := files[hdr.Name]
+		if !ok {
+			continue
+		}
+		_, err = opts.Storage.CreateFileFromStreamCtx(ctx, f.bucketID, f.ID, f.Name, tr, hdr.Size, f.Permissions)
+		switch {
+		case err == nil:
+			res.Files++
+		case !gowrite.IsConflict(err):
+			res.FileErrors = append(res.FileErrors, fmt.Errorf("file %s/%s: %w", f.bucketID, f.ID, err))
+		}
+	}
+
+	for _, c := range m.Collections {
+		summary, err := importDocuments(ctx, db, databaseID, opts.collectionID(c.ID), dir, c, opts.concurrency())
+		res.Collections[c.ID] = summary
+		if err != nil {
+			return res, fmt.Errorf("backup: collection %s: %w", c.ID, err)
+		}
+	}
+	for _, c := range m.Collections {
+		if err := linkRelationships(ctx, db, databaseID, opts.collectionID(c.ID), dir, c, res.Collections[c.ID], opts.concurrency()); err != nil {
+			return res, fmt.Errorf("backup: collection %s: %w", c.ID, err)
+		}
+	}
+	return res, nil
+}
+
+func (o RestoreOptions) collectionID(id string) string {
+	if mapped, ok := o.CollectionIDs[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+func (o RestoreOptions) bucketID(id string) string {
+	if mapped, ok := o.BucketIDs[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+func (o RestoreOptions) concurrency() int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return defaultConcurrency
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("backup: reading manifest: %w", err)
+	}
+	if hdr.Name != manifestName {
+		return nil, fmt.Errorf("backup: archive starts with %q instead of the manifest", hdr.Name)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, fmt.Errorf("backup: reading manifest: %w", err)
+	}
+	if m.Version != FormatVersion {
+		return nil, fmt.Errorf("backup: unsupported archive version %d", m.Version)
+	}
+	for _, c := range m.Collections {
+		if !validID.MatchString(c.ID) {
+			return nil, fmt.Errorf("backup: invalid collection id %q in manifest", c.ID)
+		}
+	}
+	return &m, nil
+}
+
+// createBucket creates the bucket with the archived settings unless it exists.
+func createBucket(ctx context.Context, storage *gowrite.StorageService, bucketID string, b *gowrite.Bucket) error {
+	_, err := storage.CreateBucketCtx(ctx, bucketID, b.Name, b.Permissions, b.FileSecurity, b.Enabled,
+		b.MaximumFileSize, b.AllowedFileExtensions, b.Compression, b.Encryption, b.Antivirus)
+	if gowrite.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+// splitRelationships copies the documents of a collection into two staged files: the
+// documents without relationships and, for documents that have any, their row number
+// and ID with the relationship values.
+func splitRelationships(r io.Reader, dir string, c Collection) error {
+	docs, err := os.Create(filepath.Join(dir, c.ID+".ndjson"))
+	if err != nil {
+		return err
+	}
+	defer docs.Close()
+	links, err := os.Create(filepath.Join(dir, c.ID+".links.ndjson"))
+	if err != nil {
+		return err
+	}
+	defer links.Close()
+
+	docsW, linksW := bufio.NewWriter(docs), bufio.NewWriter(links)
+	br := bufio.NewReader(r)
+	for row := 0; ; {
+		line, readErr := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if len(c.Relationships) > 0 {
+				if line, err = extractLinks(line, row, c.Relationships, linksW); err != nil {
+					return err
+				}
+			}
+			if _, err := docsW.Write(line); err != nil {
+				return err
+			}
+			row++
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if err := docsW.Flush(); err != nil {
+		return err
+	}
+	return linksW.Flush()
+}
+
+// link is a staged line of relationship values.
+type link struct {
+	Row  int                        `json:"row"`
+	ID   string                     `json:"id"`
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+// extractLinks removes the relationship keys from a document line and writes them to
+// links. Lines that are not valid JSON are passed on as is, so the import reports them.
+func extractLinks(line []byte, row int, keys []string, links io.Writer) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(line, &doc) != nil {
+		return line, nil
+	}
+	l := link{Row: row, Data: make(map[string]json.RawMessage)}
+	json.Unmarshal(doc["$id"], &l.ID)
+	for _, k := range keys {
+		v, ok := doc[k]
+		if !ok {
+			continue
+		}
+		delete(doc, k)
+		if s := string(bytes.TrimSpace(v)); s != "null" && s != "[]" {
+			l.Data[k] = v
+		}
+	}
+	if len(l.Data) > 0 {
+		b, err := json.Marshal(l)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := links.Write(append(b, '\n')); err != nil {
+			return nil, err
+		}
+	}
+	b, err := json.Marshal(doc)
+	return append(b, '\n'), err
+}
+
+// importDocuments imports the staged documents of c into collectionID.
+func importDocuments(ctx context.Context, db *gowrite.DatabaseService, databaseID, collectionID, dir string, c Collection, concurrency int) (*gowrite.ImportSummary, error) {
+	f, err := os.Open(filepath.Join(dir, c.ID+".ndjson"))
+	if errors.Is(err, os.ErrNotExist) {
+		// The collection had no documents entry.
+		return &gowrite.ImportSummary{}, nil
+	}
+	if err != nil {
+		return &gowrite.ImportSummary{}, err
+	}
+	defer f.Close()
+	return db.ImportCollectionCtx(ctx, databaseID, collectionID, f, gowrite.ImportOptions{Format: gowrite.FormatNDJSON, Concurrency: concurrency})
+}
+
+// linkRelationships sets the staged relationship values of c's documents in
+// collectionID, adding failures to summary.
+func linkRelationships(ctx context.Context, db *gowrite.DatabaseService, databaseID, collectionID, dir string, c Collection, summary *gowrite.ImportSummary, concurrency int) error {
+	f, err := os.Open(filepath.Join(dir, c.ID+".links.ndjson"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	dec := json.NewDecoder(f)
+	for {
+		var l link
+		if err := dec.Decode(&l); err == io.EOF {
+			break
+		} else if err != nil {
+			wg.Wait()
+			return err
+		}
+		data := make(map[string]interface{}, len(l.Data))
+		for k, v := range l.Data {
+			data[k] = v
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if _, err := db.UpdateDocumentCtx(ctx, databaseID, collectionID, l.ID, data, nil); err != nil {
+				mu.Lock()
+				summary.Failed = append(summary.Failed, &gowrite.BulkError{Index: l.Row, DocumentID: l.ID, Err: fmt.Errorf("linking relationships: %w", err)})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	slices.SortFunc(summary.Failed, func(a, b *gowrite.BulkError) int { return a.Index - b.Index })
+	return ctx.Err()
+}
@@ -0,0 +1,332 @@
+package gowrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dm-vev/gowrite/query"
+)
+
+const (
+	// bulkChunkSize is the number of documents sent per bulk request.
+	bulkChunkSize = 100
+	// bulkConcurrency is the number of single calls in flight when a bulk operation
+	// falls back to per-document requests.
+	bulkConcurrency = 5
+	// bulkMinVersion is the first server version with bulk document endpoints.
+	bulkMinVersion = "1.7.0"
+	// upsertMinVersion is the first server version with single document upserts and
+	// atomic increments.
+	upsertMinVersion = "1.7.0"
+)
+
+// BulkResult reports the outcome of a bulk document operation.
+type BulkResult struct {
+	// Documents holds the documents written. For CreateDocuments and UpsertDocuments
+	// it is aligned with the input, with nil entries for failed items; for
+	// UpdateDocuments and DeleteDocuments it holds the affected documents.
+	Documents []*Document
+	// Errors lists the items that failed.
+	Errors []*BulkError
+}
+
+// Err returns the item errors joined, or nil when every item succeeded.
+func (r *BulkResult) Err() error {
+	errs := make([]error, len(r.Errors))
+	for i, e := range r.Errors {
+		errs[i] = e
+	}
+	return errors.Join(errs...)
+}
+
+// BulkError is the error of a single item of a bulk operation.
+type BulkError struct {
+	// Index is the position of the item in the input, or in the matched documents
+	// for query based operations.
+	Index      int
+	DocumentID string
+	Err        error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("document %d (%s): %v", e.Index, e.DocumentID, e.Err)
+}
+
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
+
+// serverAtLeast reports whether the server is at least version min. When the version
+// cannot be determined it reports true and callers rely on the route being missing.
+func (client *AppwriteClient) serverAtLeast(ctx context.Context, min string) bool {
+	v, _ := client.ServerVersion(ctx)
+	if v == "" {
+		return true
+	}
+	return compareVersions(parseVersion(v), parseVersion(min)) >= 0
+}
+
+// isUnsupportedRoute reports whether err means the server has no such endpoint.
+func isUnsupportedRoute(err error) bool {
+	return HasErrorType(err, ErrorTypeGeneralRouteNotFound)
+}
+
+// invalidateDocumentsCache drops the cached documents and the collection's lists.
+func (db *DatabaseService) invalidateDocumentsCache(ctx context.Context, databaseID, collectionID string, docs []*Document) {
+	if !db.cacheEnabled() {
+		return
+	}
+	for _, doc := range docs {
+		if doc != nil {
+			_ = db.Cache.Delete(ctx, db.documentCacheKey(databaseID, collectionID, doc.ID))
+		}
+	}
+	db.invalidateCollectionCache(ctx, databaseID, collectionID)
+}
+
+// bulkPayload converts a document into an item of a bulk request.
+func bulkPayload(doc *Document) map[string]interface{} {
+	item := make(map[string]interface{}, len(doc.Data)+2)
+	for k, v := range doc.Data {
+		item[k] = v
+	}
+	item["$id"] = doc.ID
+	if doc.ID == "" {
+		item["$id"] = "unique()"
+	}
+	if doc.Permissions != nil {
+		item["$permissions"] = doc.Permissions
+	}
+	return item
+}
+
+// runConcurrent calls fn for every index in [0, n) with at most limit calls in
+// flight and returns the error of each call.
+func runConcurrent(n, limit int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+// CreateDocuments creates docs, using the bulk endpoint in chunks of 100 when the
+// server supports it and concurrent CreateDocument calls otherwise. A document
+// without an ID gets a unique one. A chunk the server rejects is retried document by
+// document, so that each error in the result belongs to the item that caused it; the
+// returned error is only set when the operation could not run at all.
+func (db *DatabaseService) CreateDocuments(databaseID, collectionID string, docs []*Document) (*BulkResult, error) {
+	return db.CreateDocumentsCtx(context.Background(), databaseID, collectionID, docs)
+}
+
+// CreateDocumentsCtx is like CreateDocuments but uses ctx for the requests and cache calls.
+func (db *DatabaseService) CreateDocumentsCtx(ctx context.Context, databaseID, collectionID string, docs []*Document) (_ *BulkResult, err error) {
+	op := newOperation("databases", "createDocuments", "databaseId", databaseID, "collectionId", collectionID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	return db.bulkWrite(ctx, databaseID, collectionID, docs, "POST", "createDocuments",
+		func(doc *Document) (*Document, error) {
+			id := doc.ID
+			if id == "" {
+				id = "unique()"
+			}
+			return db.CreateDocumentCtx(ctx, databaseID, collectionID, id, doc.Data, doc.Permissions)
+		})
+}
+
+// UpsertDocuments creates docs or replaces the documents with the same IDs, using the
+// bulk endpoint when the server supports it. On older servers each document is
+// created and updated instead when it already exists.
+func (db *DatabaseService) UpsertDocuments(databaseID, collectionID string, docs []*Document) (*BulkResult, error) {
+	return db.UpsertDocumentsCtx(context.Background(), databaseID, collectionID, docs)
+}
+
+// UpsertDocumentsCtx is like UpsertDocuments but uses ctx for the requests and cache calls.
+func (db *DatabaseService) UpsertDocumentsCtx(ctx context.Context, databaseID, collectionID string, docs []*Document) (_ *BulkResult, err error) {
+	op := newOperation("databases", "upsertDocuments", "databaseId", databaseID, "collectionId", collectionID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	return db.bulkWrite(ctx, databaseID, collectionID, docs, "PUT", "upsertDocuments",
+		func(doc *Document) (*Document, error) {
+			return db.upsertDocumentFallback(ctx, databaseID, collectionID, doc.ID, doc.Data, doc.Permissions)
+		})
+}
+
+// upsertDocumentFallback emulates an upsert with a create followed by an update when
+// the document already exists.
+func (db *DatabaseService) upsertDocumentFallback(ctx context.Context, databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
+	if documentID == "" {
+		documentID = "unique()"
+	}
+	doc, err := db.CreateDocumentCtx(ctx, databaseID, collectionID, documentID, data, permissions)
+	if IsConflict(err) {
+		return db.UpdateDocumentCtx(ctx, databaseID, collectionID, documentID, data, permissions)
+	}
+	return doc, err
+}
+
+// bulkWrite sends docs to the bulk endpoint in chunks, or through single calls when
+// the server lacks it.
+func (db *DatabaseService) bulkWrite(ctx context.Context, databaseID, collectionID string, docs []*Document, method, opMethod string, single func(*Document) (*Document, error)) (*BulkResult, error) {
+	result := &BulkResult{Documents: make([]*Document, len(docs))}
+	defer func() { db.invalidateDocumentsCache(ctx, databaseID, collectionID, result.Documents) }()
+
+	path := fmt.Sprintf("/databases/%s/collections/%s/documents", databaseID, collectionID)
+	start := 0
+	if db.Client.serverAtLeast(ctx, bulkMinVersion) {
+		for ; start < len(docs); start += bulkChunkSize {
+			chunk := docs[start:min(start+bulkChunkSize, len(docs))]
+			items := make([]map[string]interface{}, len(chunk))
+			for i, doc := range chunk {
+				items[i] = bulkPayload(doc)
+			}
+
+			chunkOp := newOperation("databases", opMethod, "databaseId", databaseID, "collectionId", collectionID, "offset", strconv.Itoa(start))
+			respBody, err := db.Client.sendRequest(ctx, chunkOp, method, path, map[string]interface{}{"documents": items})
+			if err != nil && start == 0 && isUnsupportedRoute(err) {
+				break
+			}
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if err != nil {
+				// The server rejects a chunk as a whole, so write its documents one
+				// by one to find out which of them failed and why.
+				writeSingle(result, start, chunk, single)
+				continue
+			}
+			var list documentList
+			if err = _json.Unmarshal(respBody, &list); err != nil {
+				for i, doc := range chunk {
+					result.Errors = append(result.Errors, &BulkError{Index: start + i, DocumentID: doc.ID, Err: err})
+				}
+				continue
+			}
+			copy(result.Documents[start:], list.Documents)
+		}
+		if start >= len(docs) {
+			return result, ctx.Err()
+		}
+	}
+
+	writeSingle(result, start, docs[start:], single)
+	return result, ctx.Err()
+}
+
+// writeSingle writes docs, which start at offset in the input, through concurrent
+// single calls and records the outcome of each in result.
+func writeSingle(result *BulkResult, offset int, docs []*Document, single func(*Document) (*Document, error)) {
+	errs := runConcurrent(len(docs), bulkConcurrency, func(i int) error {
+		doc, err := single(docs[i])
+		result.Documents[offset+i] = doc
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			result.Errors = append(result.Errors, &BulkError{Index: offset + i, DocumentID: docs[i].ID, Err: err})
+		}
+	}
+}
+
+// UpdateDocuments applies data to every document matching queries, using the bulk
+// endpoint when the server supports it and updating the matched documents one by one
+// otherwise.
+func (db *DatabaseService) UpdateDocuments(databaseID, collectionID string, queries []string, data map[string]interface{}) (*BulkResult, error) {
+	return db.UpdateDocumentsCtx(context.Background(), databaseID, collectionID, queries, data)
+}
+
+// UpdateDocumentsCtx is like UpdateDocuments but uses ctx for the requests and cache calls.
+func (db *DatabaseService) UpdateDocumentsCtx(ctx context.Context, databaseID, collectionID string, queries []string, data map[string]interface{}) (_ *BulkResult, err error) {
+	op := newOperation("databases", "updateDocuments", "databaseId", databaseID, "collectionId", collectionID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	payload := map[string]interface{}{"data": data, "queries": queries}
+	return db.bulkQuery(ctx, op, databaseID, collectionID, queries, "PATCH", payload,
+		func(doc *Document) (*Document, error) {
+			return db.UpdateDocumentCtx(ctx, databaseID, collectionID, doc.ID, data, nil)
+		})
+}
+
+// DeleteDocuments deletes every document matching queries, using the bulk endpoint
+// when the server supports it and deleting the matched documents one by one otherwise.
+func (db *DatabaseService) DeleteDocuments(databaseID, collectionID string, queries []string) (*BulkResult, error) {
+	return db.DeleteDocumentsCtx(context.Background(), databaseID, collectionID, queries)
+}
+
+// DeleteDocumentsCtx is like DeleteDocuments but uses ctx for the requests and cache calls.
+func (db *DatabaseService) DeleteDocumentsCtx(ctx context.Context, databaseID, collectionID string, queries []string) (_ *BulkResult, err error) {
+	op := newOperation("databases", "deleteDocuments", "databaseId", databaseID, "collectionId", collectionID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	payload := map[string]interface{}{"queries": queries}
+	return db.bulkQuery(ctx, op, databaseID, collectionID, queries, "DELETE", payload,
+		func(doc *Document) (*Document, error) {
+			return doc, db.DeleteDocumentCtx(ctx, databaseID, collectionID, doc.ID)
+		})
+}
+
+// bulkQuery runs a query based bulk request, or lists the matching documents and
+// calls single for each when the server lacks the endpoint.
+func (db *DatabaseService) bulkQuery(ctx context.Context, op Operation, databaseID, collectionID string, queries []string, method string, payload map[string]interface{}, single func(*Document) (*Document, error)) (*BulkResult, error) {
+	result := &BulkResult{}
+	defer func() { db.invalidateDocumentsCache(ctx, databaseID, collectionID, result.Documents) }()
+
+	if db.Client.serverAtLeast(ctx, bulkMinVersion) {
+		path := fmt.Sprintf("/databases/%s/collections/%s/documents", databaseID, collectionID)
+		respBody, err := db.Client.sendRequest(ctx, op, method, path, payload)
+		if err == nil {
+			var list documentList
+			if err = _json.Unmarshal(respBody, &list); err != nil {
+				return result, err
+			}
+			result.Documents = list.Documents
+			return result, nil
+		}
+		if !isUnsupportedRoute(err) {
+			return result, err
+		}
+	}
+
+	// Collect the matches first so that the writes do not shift the pages.
+	var matched []*Document
+	q := queries
+	if method != "DELETE" {
+		q = append(queries[:len(queries):len(queries)], query.Select([]string{"$id"}))
+	}
+	for doc, err := range db.IterateDocuments(ctx, databaseID, collectionID, q) {
+		if err != nil {
+			return result, err
+		}
+		matched = append(matched, doc)
+	}
+
+	docs := make([]*Document, len(matched))
+	errs := runConcurrent(len(matched), bulkConcurrency, func(i int) error {
+		doc, err := single(matched[i])
+		docs[i] = doc
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			result.Errors = append(result.Errors, &BulkError{Index: i, DocumentID: matched[i].ID, Err: err})
+			continue
+		}
+		result.Documents = append(result.Documents, docs[i])
+	}
+	return result, ctx.Err()
+}
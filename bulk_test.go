@@ -0,0 +1,121 @@
+package gowrite_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+)
+
+func TestCreateDocumentsChunks(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		chunks []int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/health/version" {
+			fmt.Fprint(w, `{"version":"1.7.4"}`)
+			return
+		}
+		var body struct {
+			DocumentID string                   `json:"documentId"`
+			Documents  []map[string]interface{} `json:"documents"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.DocumentID != "" {
+			if body.DocumentID == "d150" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"Invalid document structure","code":400,"type":"document_invalid_structure"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"$id":%q}`, body.DocumentID)
+			return
+		}
+		mu.Lock()
+		chunks = append(chunks, len(body.Documents))
+		failed := len(chunks) == 2
+		mu.Unlock()
+		if failed {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":"Invalid document structure","code":400,"type":"document_invalid_structure"}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": len(body.Documents), "documents": body.Documents})
+	}))
+	defer srv.Close()
+
+	docs := make([]*gowrite.Document, 250)
+	for i := range docs {
+		docs[i] = &gowrite.Document{ID: fmt.Sprintf("d%d", i), Data: map[string]interface{}{"n": i}}
+	}
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	res, err := db.CreateDocuments("db", "c", docs)
+	if err != nil {
+		t.Fatalf("CreateDocuments: %v", err)
+	}
+	if fmt.Sprint(chunks) != "[100 100 50]" {
+		t.Fatalf("chunks = %v", chunks)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Index != 150 || res.Errors[0].DocumentID != "d150" {
+		t.Fatalf("expected only d150 to fail, got %d errors", len(res.Errors))
+	}
+	if res.Documents[150] != nil || res.Documents[100] == nil || res.Documents[100].ID != "d100" {
+		t.Fatalf("Documents[100] = %+v, Documents[150] = %+v", res.Documents[100], res.Documents[150])
+	}
+	if res.Documents[249] == nil || res.Documents[249].ID != "d249" {
+		t.Fatalf("Documents[249] = %+v", res.Documents[249])
+	}
+}
+
+func TestUpsertDocumentsFallback(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.6.1"}`)
+		case r.Method == "POST":
+			var body struct {
+				DocumentID string `json:"documentId"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.DocumentID == "old" {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"message":"exists","code":409,"type":"document_already_exists"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"$id":%q}`, body.DocumentID)
+		default:
+			fmt.Fprint(w, `{"$id":"old"}`)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	res, err := db.UpsertDocuments("db", "c", []*gowrite.Document{{ID: "new"}, {ID: "old"}})
+	if err != nil || res.Err() != nil {
+		t.Fatalf("UpsertDocuments: %v, %v", err, res.Err())
+	}
+	if res.Documents[0].ID != "new" || res.Documents[1].ID != "old" {
+		t.Fatalf("Documents = %+v", res.Documents)
+	}
+	patched := false
+	for _, r := range requests {
+		if r == "PATCH /v1/databases/db/collections/c/documents/old" {
+			patched = true
+		}
+	}
+	if !patched {
+		t.Fatalf("existing document was not updated: %v", requests)
+	}
+}
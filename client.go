@@ -2,17 +2,37 @@ package gowrite
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"time"
 )
 
 type AppwriteClient struct {
-	Endpoint   string
-	ProjectID  string
-	APIKey     string
-	HTTPClient *http.Client
+	Endpoint  string
+	ProjectID string
+	APIKey    string
+	// ResponseFormat selects the model version returned by the server.
+	// DefaultResponseFormat is used when empty.
+	ResponseFormat string
+	// Credentials overrides APIKey when set, e.g. with a JWT or session.
+	Credentials Credentials
+	HTTPClient  *http.Client
+	Retry       *RetryPolicy
+	Limiter     RateLimiter
+	Observer    Observer
+	// VersionCacheTTL is how long the probed server version is reused before it is
+	// probed again; DefaultVersionCacheTTL is used when zero. Only clients built with
+	// NewClient and the copies WithCredentials makes of them cache the version.
+	VersionCacheTTL time.Duration
+
+	middlewares []Middleware
+	// versions is nil for clients built without NewClient and for their copies, which
+	// then do not cache the server version.
+	versions *versionCache
 }
 
 func NewClient(endpoint, projectID, apiKey string) *AppwriteClient {
@@ -21,31 +41,96 @@ func NewClient(endpoint, projectID, apiKey string) *AppwriteClient {
 		ProjectID:  projectID,
 		APIKey:     apiKey,
 		HTTPClient: &http.Client{},
+		versions:   &versionCache{},
 	}
 }
 
-func (client *AppwriteClient) sendRequest(method, path string, body interface{}) ([]byte, error) {
-	url := fmt.Sprintf("%s/v1%s", client.Endpoint, path)
-
-	var reqBody []byte
-	var err error
+// newRequest builds a request against the API path and sets the headers shared by every call.
+func (client *AppwriteClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	url := fmt.Sprintf("%s/v1%s", client.baseURL(), path)
 
-	if body != nil {
-		reqBody, err = json.Marshal(body)
-		if err != nil {
-			return nil, err
-		}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+	req.Header.Set("X-Appwrite-Project", client.ProjectID)
+	client.credentials(ctx).Apply(req.Header)
+	req.Header.Set("X-Appwrite-Response-Format", client.responseFormat())
+
+	return req, nil
+}
+
+// do runs the request through the middleware chain and returns the response body,
+// turning error statuses into errors.
+func (client *AppwriteClient) do(op Operation, req *http.Request) ([]byte, error) {
+	resp, err := client.handler()(&Request{Operation: op, HTTP: req})
 	if err != nil {
 		return nil, err
 	}
+	return resp.Body, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Appwrite-Project", client.ProjectID)
-	req.Header.Set("X-Appwrite-Key", client.APIKey)
-	req.Header.Set("X-Appwrite-Response-Format", "1.6.0")
+// roundTrip is the innermost Handler. Failed attempts are repeated according to the
+// client's retry policy; the request body is rewound through GetBody before each retry.
+func (client *AppwriteClient) roundTrip(r *Request) (*Response, error) {
+	req := r.HTTP
+	attempts := client.Retry.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := client.attempt(req)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= attempts || !client.Retry.shouldRetry(req, err) || errors.Is(err, errBodyInterrupted) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		var header http.Header
+		if resp != nil {
+			header = resp.Header
+		}
+		delay, ok := serverDelay(header, time.Now())
+		if !ok {
+			delay = client.Retry.backoff(attempt + 1)
+		}
+		if sleepErr := sleepCtx(req.Context(), delay); sleepErr != nil {
+			return resp, err
+		}
+	}
+}
+
+// bodyWriterKey is the context key of the writer a successful response body is copied
+// to instead of being read into Response.Body.
+type bodyWriterKey struct{}
+
+// retryableKey is the context key marking a POST or PATCH request as safe to repeat,
+// such as an upload chunk appended to an existing file.
+type retryableKey struct{}
+
+// errBodyInterrupted marks a streamed response that failed after part of it was
+// written, which cannot be retried without duplicating that part.
+var errBodyInterrupted = errors.New("gowrite: response body interrupted")
+
+// attempt performs a single round trip, waiting on the rate limiter first.
+func (client *AppwriteClient) attempt(req *http.Request) (*Response, error) {
+	group := endpointGroup(req.URL.Path)
+	if client.Limiter != nil {
+		if err := client.Limiter.Wait(req.Context(), group); err != nil {
+			return nil, err
+		}
+	}
 
 	resp, err := client.HTTPClient.Do(req)
 	if err != nil {
@@ -53,18 +138,62 @@ func (client *AppwriteClient) sendRequest(method, path string, body interface{})
 	}
 	defer resp.Body.Close()
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	if client.Limiter != nil {
+		client.Limiter.Observe(group, resp.Header)
+	}
+
+	if w, ok := req.Context().Value(bodyWriterKey{}).(io.Writer); ok && resp.StatusCode < 400 {
+		n, err := io.Copy(w, resp.Body)
+		out := &Response{StatusCode: resp.StatusCode, Header: resp.Header, BodySize: n}
+		if err != nil {
+			if n > 0 {
+				return out, fmt.Errorf("%w after %d bytes: %w", errBodyInterrupted, n, err)
+			}
+			return out, err
+		}
+		return out, nil
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	out := &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody, BodySize: int64(len(respBody))}
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+		return out, newAppwriteError(resp.StatusCode, respBody)
+	}
+
+	if err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
+
+func (client *AppwriteClient) sendRequest(ctx context.Context, op Operation, method, path string, body interface{}) ([]byte, error) {
+	var reqBody []byte
+	var err error
+
+	if body != nil {
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	req, err := client.newRequest(ctx, method, path, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
 
-	return respBody, nil
+	req.Header.Set("Content-Type", "application/json")
+
+	return client.do(op, req)
 }
 
 func (client *AppwriteClient) SendRequest(method, path string, body interface{}) ([]byte, error) {
-	return client.sendRequest(method, path, body)
+	return client.SendRequestCtx(context.Background(), method, path, body)
+}
+
+// SendRequestCtx is like SendRequest but uses ctx for the request.
+func (client *AppwriteClient) SendRequestCtx(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	op := newOperation(endpointGroup(path), "request", "path", path)
+	return client.sendRequest(ctx, op, method, path, body)
 }
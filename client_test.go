@@ -0,0 +1,326 @@
+package gowrite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+)
+
+func TestAppwriteErrorDecoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Document with the requested ID could not be found.","code":404,"type":"document_not_found","version":"1.6.0"}`)
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "project", "key"))
+	_, err := db.GetDocument("db", "col", "missing")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	wrapped := fmt.Errorf("loading: %w", err)
+	var appErr *gowrite.AppwriteError
+	if !errors.As(wrapped, &appErr) {
+		t.Fatalf("errors.As failed for %T", err)
+	}
+	if appErr.StatusCode != 404 || appErr.Code != 404 || appErr.Type != gowrite.ErrorTypeDocumentNotFound || appErr.Version != "1.6.0" {
+		t.Fatalf("unexpected error fields: %+v", appErr)
+	}
+	if !gowrite.IsNotFound(wrapped) || gowrite.IsConflict(wrapped) || gowrite.IsRateLimited(wrapped) {
+		t.Fatal("status helpers mismatch")
+	}
+	if !gowrite.HasErrorType(wrapped, gowrite.ErrorTypeDocumentNotFound) {
+		t.Fatal("HasErrorType mismatch")
+	}
+}
+
+func TestAppwriteErrorNonJSONBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "bad gateway")
+	}))
+	defer srv.Close()
+
+	client := gowrite.NewClient(srv.URL, "project", "key")
+	_, err := client.SendRequest("GET", "/health", nil)
+
+	appErr, ok := gowrite.AsAppwriteError(err)
+	if !ok {
+		t.Fatalf("expected *AppwriteError, got %T", err)
+	}
+	if appErr.Code != http.StatusBadGateway || appErr.Error() != "HTTP 502: bad gateway" {
+		t.Fatalf("unexpected error: %v (%+v)", appErr, appErr)
+	}
+}
+
+func fastRetry() *gowrite.RetryPolicy {
+	p := gowrite.DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = time.Millisecond
+	return p
+}
+
+func TestRetryIdempotentRequests(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"$id":"db","name":"main","enabled":true}`)
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "project", "key").WithRetry(fastRetry()))
+	got, err := db.GetDatabase("db")
+	if err != nil {
+		t.Fatalf("GetDatabase: %v", err)
+	}
+	if got.ID != "db" || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("unexpected result %+v after %d calls", got, calls)
+	}
+}
+
+func TestRetrySkipsNonIdempotentRequests(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "project", "key").WithRetry(fastRetry()))
+	if _, err := db.CreateDatabase("db", "main", true); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("POST attempted %d times, want 1", n)
+	}
+}
+
+func TestRetryRewindsMultipartBody(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("FormFile: %v", err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if string(content) != "payload" {
+			t.Errorf("attempt %d got body %q", atomic.LoadInt32(&calls)+1, content)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"$id":"file","bucketId":"bucket","name":"upload.txt"}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(path, []byte("payload"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := gowrite.NewStorage(gowrite.NewClient(srv.URL, "project", "key").WithRetry(fastRetry()))
+	file, err := storage.CreateFile("bucket", "file", path, nil)
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	if file.ID != "file" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("unexpected result %+v after %d calls", file, calls)
+	}
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "on" {
+			t.Errorf("%s %s: middleware header missing", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	var seen []string
+	client := gowrite.NewClient(srv.URL, "project", "key")
+	client.Use(func(next gowrite.Handler) gowrite.Handler {
+		return func(req *gowrite.Request) (*gowrite.Response, error) {
+			req.HTTP.Header.Set("X-Trace", "on")
+			resp, err := next(req)
+			seen = append(seen, fmt.Sprintf("%s %v %d", req.Operation.Name(), req.Operation.Params, resp.StatusCode))
+			return resp, err
+		}
+	})
+
+	if _, err := gowrite.NewDatabases(client).GetDocument("db", "col", "doc"); err != nil {
+		t.Fatalf("GetDocument: %v", err)
+	}
+	if _, err := gowrite.NewStorage(client).DownloadFile("bucket", "file"); err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+
+	want := []string{
+		"databases.getDocument map[collectionId:col databaseId:db documentId:doc] 200",
+		"storage.getFileDownload map[bucketId:bucket fileId:file] 200",
+	}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Fatalf("middleware saw %q, want %q", seen, want)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, fmt.Sprintf("key=%q jwt=%q session=%q",
+			r.Header.Get("X-Appwrite-Key"), r.Header.Get("X-Appwrite-JWT"), r.Header.Get("X-Appwrite-Session")))
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	client := gowrite.NewClient(srv.URL, "project", "secret")
+	userClient := client.WithJWT("token")
+
+	if _, err := gowrite.NewDatabases(client).GetDatabase("db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gowrite.NewDatabases(userClient).GetDatabase("db"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := gowrite.ContextWithCredentials(context.Background(), gowrite.Session("sess"))
+	if _, err := gowrite.NewDatabases(client).GetDatabaseCtx(ctx, "db"); err != nil {
+		t.Fatal(err)
+	}
+	ctx = gowrite.ContextWithCredentials(context.Background(), gowrite.Anonymous{})
+	if _, err := gowrite.NewDatabases(client).GetDatabaseCtx(ctx, "db"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`key="secret" jwt="" session=""`,
+		`key="" jwt="token" session=""`,
+		`key="" jwt="" session="sess"`,
+		`key="" jwt="" session=""`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("headers %q, want %q", got, want)
+	}
+}
+
+func TestProbeNormalizesEndpoint(t *testing.T) {
+	version := "1.6.2"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/version" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Appwrite-Response-Format"); got != "1.6.0" {
+			t.Errorf("response format %q", got)
+		}
+		fmt.Fprintf(w, `{"version":%q}`, version)
+	}))
+	defer srv.Close()
+
+	client := gowrite.NewClient(srv.URL+"/v1/", "project", "key")
+	sv, err := client.Probe(context.Background())
+	if err != nil || sv.Version != "1.6.2" || sv.Warning != "" {
+		t.Fatalf("Probe = %+v, %v", sv, err)
+	}
+
+	version = "1.7.4"
+	if sv, err = client.Probe(context.Background()); err != nil || sv.Warning == "" {
+		t.Fatalf("expected a warning for a newer server, got %+v, %v", sv, err)
+	}
+
+	version = "1.5.7"
+	_, err = client.Probe(context.Background())
+	var incompatible *gowrite.IncompatibleVersionError
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("expected IncompatibleVersionError, got %v", err)
+	}
+}
+
+func TestServerVersionCache(t *testing.T) {
+	var (
+		probes  atomic.Int32
+		version atomic.Value
+	)
+	version.Store("1.6.2")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		v := version.Load().(string)
+		if v == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"message":"unavailable","code":503,"type":"general_server_error"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"version":%q}`, v)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	client := gowrite.NewClient(srv.URL, "project", "key")
+	for i := 0; i < 2; i++ {
+		if v, err := client.ServerVersion(ctx); v != "1.6.2" || err != nil {
+			t.Fatalf("ServerVersion = %q, %v", v, err)
+		}
+	}
+	if n := probes.Load(); n != 1 {
+		t.Fatalf("probed %d times, want 1", n)
+	}
+
+	// Clients built without NewClient, and the clients derived from them, have no
+	// cache and probe every time.
+	probes.Store(0)
+	literal := &gowrite.AppwriteClient{Endpoint: srv.URL, ProjectID: "project", HTTPClient: srv.Client()}
+	for i := 0; i < 2; i++ {
+		if v, err := literal.ServerVersion(ctx); v != "1.6.2" || err != nil {
+			t.Fatalf("ServerVersion of a client literal = %q, %v", v, err)
+		}
+	}
+	if n := probes.Load(); n != 2 {
+		t.Fatalf("client literal probed %d times, want 2", n)
+	}
+	probes.Store(0)
+	derived := literal.WithJWT("jwt")
+	for i := 0; i < 2; i++ {
+		if v, err := derived.WithSession("secret").ServerVersion(ctx); v != "1.6.2" || err != nil {
+			t.Fatalf("ServerVersion of a derived client = %q, %v", v, err)
+		}
+	}
+	if n := probes.Load(); n != 2 {
+		t.Fatalf("derived clients probed %d times, want 2", n)
+	}
+
+	// Another client of the same endpoint does not see the cached version.
+	version.Store("1.5.7")
+	v, err := gowrite.NewClient(srv.URL, "project", "key").ServerVersion(ctx)
+	var incompatible *gowrite.IncompatibleVersionError
+	if v != "1.5.7" || !errors.As(err, &incompatible) {
+		t.Fatalf("ServerVersion of a downgraded server = %q, %v", v, err)
+	}
+
+	client.ResetServerVersion()
+	version.Store("")
+	probes.Store(0)
+	for i := 0; i < 2; i++ {
+		if _, err := client.ServerVersion(ctx); err == nil {
+			t.Fatal("expected the probe error")
+		}
+	}
+	if n := probes.Load(); n != 1 {
+		t.Fatalf("failed probe repeated %d times, want 1", n)
+	}
+}
@@ -0,0 +1,120 @@
+// Command gowrite is a command line companion for the gowrite client.
+//
+// Usage:
+//
+//	gowrite gen [flags] [database ids]
+//
+// gen writes Go types for the collections of the given databases, or of all databases
+// when none are given. The schema is read from the project configured by the
+// APPWRITE_INSTANCE, APPWRITE_PROJECT and APPWRITE_TOKEN environment variables (also
+// loaded from a .env file) or from a schema file passed with -schema.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/codegen"
+	"github.com/dm-vev/gowrite/schema"
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: gowrite <command> [flags]
+
+commands:
+  gen    generate Go types for collections
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = gen(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "gowrite: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gowrite:", err)
+		os.Exit(1)
+	}
+}
+
+func gen(args []string) error {
+	// A missing .env file is fine; the variables may come from the environment.
+	_ = godotenv.Load()
+
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gowrite gen [flags] [database ids]")
+		fs.PrintDefaults()
+	}
+	schemaFile := fs.String("schema", "", "read the schema from a JSON or YAML `file` instead of the project")
+	pkg := fs.String("package", "models", "package `name` of the generated file")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	endpoint := fs.String("endpoint", os.Getenv("APPWRITE_INSTANCE"), "Appwrite endpoint")
+	project := fs.String("project", os.Getenv("APPWRITE_PROJECT"), "project ID")
+	key := fs.String("key", os.Getenv("APPWRITE_TOKEN"), "API key")
+	timeout := fs.Duration("timeout", time.Minute, "timeout for reading the project")
+	fs.Parse(args)
+
+	var (
+		s   *schema.Schema
+		err error
+	)
+	if *schemaFile != "" {
+		// Generate validates the file itself and keeps attributes of unknown types.
+		s, err = schema.LoadUnvalidated(*schemaFile)
+	} else {
+		if *endpoint == "" || *project == "" || *key == "" {
+			return fmt.Errorf("missing endpoint, project or key; set APPWRITE_INSTANCE, APPWRITE_PROJECT and APPWRITE_TOKEN or pass -schema")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		databases := gowrite.NewDatabases(gowrite.NewClient(*endpoint, *project, *key))
+		s, err = schema.Inspect(ctx, databases, fs.Args()...)
+	}
+	if err != nil {
+		return err
+	}
+	if *schemaFile != "" && fs.NArg() > 0 {
+		s = filterDatabases(s, fs.Args())
+	}
+
+	src, err := codegen.Generate(s, codegen.Options{Package: *pkg})
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*output, src, 0o644)
+}
+
+// filterDatabases keeps the databases of s with the given IDs.
+func filterDatabases(s *schema.Schema, ids []string) *schema.Schema {
+	keep := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+	out := &schema.Schema{}
+	for _, d := range s.Databases {
+		if keep[d.ID] {
+			out.Databases = append(out.Databases, d)
+		}
+	}
+	return out
+}
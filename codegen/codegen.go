@@ -0,0 +1,318 @@
+// Package codegen generates Go types for the collections of a schema.
+//
+// For every collection it emits a struct embedding gowrite.DocumentMeta with a field
+// per attribute, ready for gowrite.DecodeDocument and the other typed helpers, along
+// with constants for the database and collection IDs and the attribute keys:
+//
+//	// Posts is a document of the blog/posts collection.
+//	type Posts struct {
+//		gowrite.DocumentMeta
+//		Title     string      `json:"title"`
+//		Status    PostsStatus `json:"status"`
+//		Published *time.Time  `json:"published,omitempty"`
+//		Author    *Users      `json:"author,omitempty"`
+//	}
+//
+// Enum attributes get a string type with a constant per element. Relationships become
+// the generated type of the related collection, a slice for the to-many side.
+// Attributes of types unknown to gowrite are kept as interface{}.
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/schema"
+)
+
+// Options tune the generated code.
+type Options struct {
+	// Package is the package name of the generated file, "models" when empty.
+	Package string
+}
+
+// initialisms are written in upper case in generated names.
+var initialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sql": true, "uid": true, "uri": true, "url": true, "uuid": true,
+}
+
+// builtinTypes are the attribute types with a Go mapping.
+var builtinTypes = []gowrite.AttributeType{
+	gowrite.AttributeString, gowrite.AttributeInteger, gowrite.AttributeFloat, gowrite.AttributeBoolean,
+	gowrite.AttributeDatetime, gowrite.AttributeEmail, gowrite.AttributeIP, gowrite.AttributeURL,
+	gowrite.AttributeEnum, gowrite.AttributeRelationship,
+}
+
+func known(a schema.Attribute) bool {
+	return slices.Contains(builtinTypes, gowrite.AttributeType(a.Type))
+}
+
+// metaFields are promoted from gowrite.DocumentMeta and cannot name attributes.
+var metaFields = map[string]bool{
+	"DocumentMeta": true, "ID": true, "Sequence": true, "Collection": true,
+	"Database": true, "CreatedAt": true, "UpdatedAt": true, "Permissions": true,
+}
+
+// Generate returns the gofmt-ed source of the types for s.
+func Generate(s *schema.Schema, opts Options) ([]byte, error) {
+	if err := knownTypes(s).Validate(); err != nil {
+		return nil, err
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "models"
+	}
+
+	g := &generator{s: s, used: make(map[string]bool), types: make(map[[2]string]string)}
+	g.nameTypes()
+
+	for _, d := range s.Databases {
+		idConst := g.unique(exported(d.ID) + "DatabaseID")
+		fmt.Fprintf(&g.buf, "\n// %s is the ID of the %s database.\nconst %s = %q\n", idConst, d.ID, idConst, d.ID)
+		for i := range d.Collections {
+			if err := g.collection(d.ID, &d.Collections[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "// Code generated by gowrite gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	if g.usesTime {
+		out.WriteString("\t\"time\"\n\n")
+	}
+	out.WriteString("\t\"github.com/dm-vev/gowrite\"\n)\n")
+	out.WriteString(g.buf.String())
+
+	src, err := format.Source([]byte(out.String()))
+	if err != nil {
+		return nil, fmt.Errorf("codegen: formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	s   *schema.Schema
+	buf strings.Builder
+	// used holds the package level names taken so far.
+	used map[string]bool
+	// types maps database and collection IDs to struct names.
+	types map[[2]string]string
+	// usesTime is set once a datetime attribute needs the time import.
+	usesTime bool
+}
+
+// nameTypes picks the struct names up front so relationships can refer to collections
+// declared later. Collection IDs used in several databases are prefixed with the
+// database.
+func (g *generator) nameTypes() {
+	count := make(map[string]int)
+	for _, d := range g.s.Databases {
+		for _, c := range d.Collections {
+			count[c.ID]++
+		}
+	}
+	for _, d := range g.s.Databases {
+		for _, c := range d.Collections {
+			name := exported(c.ID)
+			if count[c.ID] > 1 {
+				name = exported(d.ID) + name
+			}
+			g.types[[2]string{d.ID, c.ID}] = g.unique(name)
+		}
+	}
+}
+
+// knownTypes returns a copy of s without the attributes of unknown types, which
+// Validate rejects but the generator keeps as interface{}.
+func knownTypes(s *schema.Schema) *schema.Schema {
+	out := &schema.Schema{Databases: slices.Clone(s.Databases)}
+	for i := range out.Databases {
+		d := &out.Databases[i]
+		d.Collections = slices.Clone(d.Collections)
+		for j := range d.Collections {
+			c := &d.Collections[j]
+			c.Attributes = slices.DeleteFunc(slices.Clone(c.Attributes), func(a schema.Attribute) bool {
+				return !known(a)
+			})
+		}
+	}
+	return out
+}
+
+// unique appends a number to name while it is taken.
+func (g *generator) unique(name string) string {
+	candidate := name
+	for i := 2; g.used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	g.used[candidate] = true
+	return candidate
+}
+
+func (g *generator) collection(databaseID string, c *schema.Collection) error {
+	typeName := g.types[[2]string{databaseID, c.ID}]
+	idConst := g.unique(typeName + "CollectionID")
+	fmt.Fprintf(&g.buf, "\n// %s is the ID of the %s/%s collection.\nconst %s = %q\n", idConst, databaseID, c.ID, idConst, c.ID)
+
+	type field struct{ name, typ, tag, comment string }
+	var (
+		fields []field
+		keys   []string
+		enums  strings.Builder
+	)
+	fieldNames := make(map[string]bool)
+	for _, a := range c.Attributes {
+		fname := exported(a.Key)
+		if metaFields[fname] {
+			fname += "Attr"
+		}
+		for base, i := fname, 2; fieldNames[fname]; i++ {
+			fname = base + strconv.Itoa(i)
+		}
+		fieldNames[fname] = true
+
+		typ, comment := g.goType(databaseID, typeName, fname, a, &enums), ""
+		if !known(a) {
+			comment = fmt.Sprintf(" // attribute type %q has no Go mapping", a.Type)
+		}
+		tag := a.Key
+		if !a.Required || a.Array || strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || typ == "interface{}" {
+			tag += ",omitempty"
+		}
+		fields = append(fields, field{fname, typ, tag, comment})
+
+		keyConst := g.unique(typeName + "Attr" + fname)
+		keys = append(keys, fmt.Sprintf("\t%s = %q\n", keyConst, a.Key))
+	}
+
+	if len(keys) > 0 {
+		fmt.Fprintf(&g.buf, "\n// Attribute keys of %s, for use with the query package.\nconst (\n", typeName)
+		for _, k := range keys {
+			g.buf.WriteString(k)
+		}
+		g.buf.WriteString(")\n")
+	}
+	g.buf.WriteString(enums.String())
+
+	fmt.Fprintf(&g.buf, "\n// %s is a document of the %s/%s collection.\ntype %s struct {\n\tgowrite.DocumentMeta\n", typeName, databaseID, c.ID, typeName)
+	for _, f := range fields {
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`%s\n", f.name, f.typ, f.tag, f.comment)
+	}
+	g.buf.WriteString("}\n")
+	return nil
+}
+
+// goType returns the Go type of an attribute, interface{} for unknown types. Enum
+// types are declared into enums.
+func (g *generator) goType(databaseID, typeName, fieldName string, a schema.Attribute, enums *strings.Builder) string {
+	var typ string
+	switch gowrite.AttributeType(a.Type) {
+	case gowrite.AttributeString, gowrite.AttributeEmail, gowrite.AttributeIP, gowrite.AttributeURL:
+		typ = "string"
+	case gowrite.AttributeInteger:
+		typ = "int64"
+	case gowrite.AttributeFloat:
+		typ = "float64"
+	case gowrite.AttributeBoolean:
+		typ = "bool"
+	case gowrite.AttributeDatetime:
+		typ = "time.Time"
+		g.usesTime = true
+	case gowrite.AttributeEnum:
+		typ = g.unique(typeName + fieldName)
+		fmt.Fprintf(enums, "\n// %s holds the values of the %s attribute.\ntype %s string\n\n", typ, a.Key, typ)
+		if len(a.Elements) > 0 {
+			enums.WriteString("const (\n")
+			for _, e := range a.Elements {
+				fmt.Fprintf(enums, "\t%s %s = %q\n", g.unique(typ+exported(e)), typ, e)
+			}
+			enums.WriteString(")\n")
+		}
+	case gowrite.AttributeRelationship:
+		related, ok := g.types[[2]string{databaseID, a.RelatedCollection}]
+		if !ok {
+			// Without a generated type the relationship is kept as IDs.
+			related = "string"
+		}
+		if toMany(a) {
+			return "[]" + related
+		}
+		if related == "string" {
+			return related
+		}
+		return "*" + related
+	default:
+		return "interface{}"
+	}
+
+	switch {
+	case a.Array:
+		return "[]" + typ
+	case !a.Required:
+		return "*" + typ
+	}
+	return typ
+}
+
+// toMany reports whether a relationship attribute holds several documents; the child
+// side of one-to-many and many-to-one relationships has the opposite cardinality.
+func toMany(a schema.Attribute) bool {
+	switch gowrite.RelationType(a.RelationType) {
+	case gowrite.RelationManyToMany:
+		return true
+	case gowrite.RelationOneToMany:
+		return a.Side != "child"
+	case gowrite.RelationManyToOne:
+		return a.Side == "child"
+	}
+	return false
+}
+
+// exported turns an ID or key such as "blog_posts" or "authorId" into an exported Go
+// identifier such as "BlogPosts" or "AuthorID".
+func exported(s string) string {
+	var (
+		b     strings.Builder
+		word  []rune
+		prevL bool
+	)
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := string(word)
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+		} else {
+			b.WriteRune(unicode.ToUpper(word[0]))
+			b.WriteString(string(word[1:]))
+		}
+		word = word[:0]
+	}
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			prevL = false
+			continue
+		case unicode.IsUpper(r) && prevL:
+			flush()
+		}
+		word = append(word, r)
+		prevL = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	flush()
+
+	out := b.String()
+	if out == "" || unicode.IsDigit([]rune(out)[0]) {
+		out = "X" + out
+	}
+	return out
+}
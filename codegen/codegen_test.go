@@ -0,0 +1,124 @@
+package codegen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dm-vev/gowrite/codegen"
+	"github.com/dm-vev/gowrite/schema"
+)
+
+const blogSchema = `
+databases:
+  - id: blog
+    name: Blog
+    collections:
+      - id: users
+        name: Users
+        attributes:
+          - {key: email, type: email, required: true}
+      - id: blog_posts
+        name: Posts
+        attributes:
+          - {key: title, type: string, size: 255, required: true}
+          - {key: status, type: enum, elements: [draft, in-review], required: true}
+          - {key: publishedAt, type: datetime}
+          - {key: tags, type: string, size: 32, array: true}
+          - {key: id, type: integer}
+          - {key: author, type: relationship, relatedCollection: users, relationType: manyToOne}
+          - {key: editors, type: relationship, relatedCollection: users, relationType: manyToMany}
+`
+
+func TestGenerate(t *testing.T) {
+	s, err := schema.Decode(strings.NewReader(blogSchema))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	src, err := codegen.Generate(s, codegen.Options{Package: "blog"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	out := string(src)
+
+	for _, want := range []string{
+		"package blog\n",
+		`"time"`,
+		`const BlogDatabaseID = "blog"`,
+		`const BlogPostsCollectionID = "blog_posts"`,
+		`BlogPostsAttrPublishedAt = "publishedAt"`,
+		"type BlogPostsStatus string",
+		`BlogPostsStatusInReview BlogPostsStatus = "in-review"`,
+		"Title       string          `json:\"title\"`",
+		"Status      BlogPostsStatus `json:\"status\"`",
+		"PublishedAt *time.Time      `json:\"publishedAt,omitempty\"`",
+		"Tags        []string        `json:\"tags,omitempty\"`",
+		"IDAttr      *int64          `json:\"id,omitempty\"`",
+		"Author      *Users          `json:\"author,omitempty\"`",
+		"Editors     []Users         `json:\"editors,omitempty\"`",
+		"type Users struct {\n\tgowrite.DocumentMeta\n\tEmail string `json:\"email\"`\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateChildSideAndUnknownTypes(t *testing.T) {
+	// A schema as Inspect reads it: the users collection carries the child side of the
+	// two-way author relationship, and places use a type gowrite does not model.
+	s := &schema.Schema{Databases: []schema.Database{{ID: "blog", Collections: []schema.Collection{
+		{ID: "users", Attributes: []schema.Attribute{
+			{Key: "posts", Type: "relationship", RelatedCollection: "posts", RelationType: "manyToOne", TwoWay: true, TwoWayKey: "author", Side: "child"},
+		}},
+		{ID: "posts", Attributes: []schema.Attribute{
+			{Key: "author", Type: "relationship", RelatedCollection: "users", RelationType: "manyToOne", TwoWay: true, TwoWayKey: "posts"},
+			{Key: "location", Type: "point"},
+		}},
+	}}}}
+	src, err := codegen.Generate(s, codegen.Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"Posts []Posts `json:\"posts,omitempty\"`",
+		"Author   *Users      `json:\"author,omitempty\"`",
+		"Location interface{} `json:\"location,omitempty\"` // attribute type \"point\" has no Go mapping",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateFromFileWithUnknownTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	src := `
+databases:
+  - id: geo
+    collections:
+      - id: places
+        attributes:
+          - {key: name, type: string, size: 64}
+          - {key: location, type: point}
+`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schema.Load(path); err == nil {
+		t.Fatal("Load accepted an unknown attribute type")
+	}
+	s, err := schema.LoadUnvalidated(path)
+	if err != nil {
+		t.Fatalf("LoadUnvalidated: %v", err)
+	}
+	out, err := codegen.Generate(s, codegen.Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if want := "Location interface{} `json:\"location,omitempty\"`"; !strings.Contains(string(out), want) {
+		t.Errorf("generated code lacks %q:\n%s", want, out)
+	}
+}
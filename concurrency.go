@@ -0,0 +1,264 @@
+package gowrite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/dm-vev/gowrite/query"
+)
+
+// maxUpdateAttempts bounds the read-mutate-write loop of UpdateDocumentIfUnchanged.
+const maxUpdateAttempts = 5
+
+// WithVersionAttribute makes UpdateDocumentIfUnchanged detect conflicts with the
+// integer attribute name, which it increments on every update, instead of $updatedAt.
+func (db *DatabaseService) WithVersionAttribute(name string) *DatabaseService {
+	db.VersionAttribute = name
+	return db
+}
+
+// UpdateDocumentIfUnchanged applies mutate to a copy of doc and stores the result only
+// if the document was not modified since doc was read. On a conflict the document is
+// read again and mutate is called on the fresh copy, up to five times, after which a
+// *ConflictError is returned. doc must carry its ID, Database and Collection, as
+// documents returned by this package do. mutate may change Data and Permissions;
+// only the attributes it changed are sent, and keys it removes are left as stored.
+// Returning an error aborts the update.
+//
+// Changes are detected with the service's VersionAttribute when set and with
+// $updatedAt otherwise. On Appwrite 1.7 and later the check and the write happen in
+// one conditional request. Older servers, and collections with relationship
+// attributes, which bulk updates do not support, compare a fresh read right before
+// the write instead, which leaves a short window for lost updates.
+func (db *DatabaseService) UpdateDocumentIfUnchanged(doc *Document, mutate func(*Document) error) (*Document, error) {
+	return db.UpdateDocumentIfUnchangedCtx(context.Background(), doc, mutate)
+}
+
+// UpdateDocumentIfUnchangedCtx is like UpdateDocumentIfUnchanged but uses ctx for the requests and cache calls.
+func (db *DatabaseService) UpdateDocumentIfUnchangedCtx(ctx context.Context, doc *Document, mutate func(*Document) error) (_ *Document, err error) {
+	op := newOperation("databases", "updateDocumentIfUnchanged", "databaseId", doc.Database, "collectionId", doc.Collection, "documentId", doc.ID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	current := doc
+	if !db.hasRevision(current) {
+		if current, err = db.fetchDocument(ctx, doc.Database, doc.Collection, doc.ID); err != nil {
+			return nil, err
+		}
+	}
+	conditional, err := db.supportsConditionalUpdate(ctx, doc.Database, doc.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; attempt <= maxUpdateAttempts; attempt++ {
+		next := cloneDocument(current)
+		if err := mutate(next); err != nil {
+			return nil, err
+		}
+		if db.VersionAttribute != "" {
+			if next.Data == nil {
+				// mutate may clear Data; the revision still has to be written.
+				next.Data = make(map[string]interface{})
+			}
+			next.Data[db.VersionAttribute] = parseSequence(current.Data[db.VersionAttribute]) + 1
+		}
+
+		updated, err := db.updateIfMatch(ctx, current, next, conditional)
+		if err != nil {
+			return nil, err
+		}
+		if updated != nil {
+			return updated, nil
+		}
+
+		if current, err = db.fetchDocument(ctx, doc.Database, doc.Collection, doc.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &ConflictError{DatabaseID: doc.Database, CollectionID: doc.Collection, DocumentID: doc.ID, Attempts: maxUpdateAttempts}
+}
+
+// hasRevision reports whether doc carries what is needed to detect a change.
+func (db *DatabaseService) hasRevision(doc *Document) bool {
+	if db.VersionAttribute != "" {
+		_, ok := doc.Data[db.VersionAttribute]
+		return ok
+	}
+	return !doc.UpdatedAt.IsZero()
+}
+
+// revisionQuery matches the document only while it is at the revision of doc.
+// Documents created before the version attribute existed hold null, which equal never
+// matches.
+func (db *DatabaseService) revisionQuery(doc *Document) string {
+	if db.VersionAttribute != "" {
+		v := doc.Data[db.VersionAttribute]
+		if v == nil || v == "" {
+			return query.IsNull(db.VersionAttribute)
+		}
+		return query.Equal(db.VersionAttribute, parseSequence(v))
+	}
+	return query.Equal("$updatedAt", formatTimestamp(doc.UpdatedAt))
+}
+
+// supportsConditionalUpdate reports whether updates of the collection can be made
+// conditional through the bulk update endpoint, which needs Appwrite 1.7 and is
+// rejected for collections with relationship attributes.
+func (db *DatabaseService) supportsConditionalUpdate(ctx context.Context, databaseID, collectionID string) (bool, error) {
+	if !db.Client.serverAtLeast(ctx, bulkMinVersion) {
+		return false, nil
+	}
+	col, err := db.GetCollectionCtx(ctx, databaseID, collectionID)
+	if err != nil {
+		return false, err
+	}
+	attrs, err := col.TypedAttributes()
+	if err != nil {
+		return false, err
+	}
+	for _, a := range attrs {
+		if a.Base().Type == string(AttributeRelationship) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// changedData returns the attributes of next that differ from current.
+func changedData(current, next *Document) map[string]interface{} {
+	data := make(map[string]interface{}, len(next.Data))
+	for k, v := range next.Data {
+		if old, ok := current.Data[k]; !ok || !reflect.DeepEqual(old, v) {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// sameRevision reports whether a and b are at the same revision.
+func (db *DatabaseService) sameRevision(a, b *Document) bool {
+	if db.VersionAttribute != "" {
+		return parseSequence(a.Data[db.VersionAttribute]) == parseSequence(b.Data[db.VersionAttribute])
+	}
+	return a.UpdatedAt.Equal(b.UpdatedAt)
+}
+
+// updateIfMatch writes the changes from current to next if the stored document is
+// still at the revision of current. It returns a nil document when it is not.
+// conditional selects the single conditional request over read-compare-update.
+func (db *DatabaseService) updateIfMatch(ctx context.Context, current, next *Document, conditional bool) (*Document, error) {
+	databaseID, collectionID, documentID := current.Database, current.Collection, current.ID
+	data := changedData(current, next)
+	var permissions []string
+	if next.Permissions != nil && !slices.Equal(current.Permissions, next.Permissions) {
+		permissions = next.Permissions
+	}
+
+	if conditional {
+		body := make(map[string]interface{}, len(data)+1)
+		for k, v := range data {
+			body[k] = v
+		}
+		if permissions != nil {
+			body["$permissions"] = permissions
+		}
+		payload := map[string]interface{}{
+			"data":    body,
+			"queries": []string{query.Equal("$id", documentID), db.revisionQuery(current)},
+		}
+
+		path := fmt.Sprintf("/databases/%s/collections/%s/documents", databaseID, collectionID)
+		op := newOperation("databases", "updateDocuments", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
+		respBody, err := db.Client.sendRequest(ctx, op, "PATCH", path, payload)
+		if err == nil {
+			var list documentList
+			if err = _json.Unmarshal(respBody, &list); err != nil {
+				return nil, err
+			}
+			db.invalidateDocumentCache(ctx, databaseID, collectionID, documentID)
+			if len(list.Documents) == 0 {
+				return nil, nil
+			}
+			return list.Documents[0], nil
+		}
+		if !isUnsupportedRoute(err) {
+			return nil, err
+		}
+	}
+
+	latest, err := db.fetchDocument(ctx, databaseID, collectionID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if !db.sameRevision(current, latest) {
+		return nil, nil
+	}
+	return db.UpdateDocumentCtx(ctx, databaseID, collectionID, documentID, data, permissions)
+}
+
+// fetchDocument reads a document from the server, bypassing the cache.
+func (db *DatabaseService) fetchDocument(ctx context.Context, databaseID, collectionID, documentID string) (*Document, error) {
+	path := fmt.Sprintf("/databases/%s/collections/%s/documents/%s", databaseID, collectionID, documentID)
+	op := newOperation("databases", "getDocument", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	document := Document{ID: documentID, Database: databaseID, Collection: collectionID}
+	if err = document.UnmarshalJSON(respBody); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// cloneDocument copies doc so that its data and permissions can be changed freely,
+// including the arrays and objects nested in the data.
+func cloneDocument(doc *Document) *Document {
+	out := *doc
+	out.Data = make(map[string]interface{}, len(doc.Data))
+	for k, v := range doc.Data {
+		if v != nil {
+			v = copyValue(reflect.ValueOf(v)).Interface()
+		}
+		out.Data[k] = v
+	}
+	out.Permissions = append([]string(nil), doc.Permissions...)
+	return &out
+}
+
+// copyValue copies the maps and slices in v recursively and keeps their types, so
+// that changedData sees edits made in place. Other values are returned as they are.
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(copyValue(v.Elem()))
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return out
+	}
+	return v
+}
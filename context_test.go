@@ -0,0 +1,119 @@
+package gowrite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+)
+
+func TestCtxCancelsRequest(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	client := gowrite.NewClient(srv.URL, "p", "k")
+
+	calls := map[string]func(ctx context.Context) error{
+		"GetDocumentCtx": func(ctx context.Context) error {
+			_, err := gowrite.NewDatabases(client).GetDocumentCtx(ctx, "db", "c", "d1")
+			return err
+		},
+		"GetFileCtx": func(ctx context.Context) error {
+			_, err := gowrite.NewStorage(client).GetFileCtx(ctx, "b", "f1")
+			return err
+		},
+		"GetUserCtx": func(ctx context.Context) error {
+			_, err := gowrite.NewUsers(client).GetUserCtx(ctx, "u1")
+			return err
+		},
+	}
+	for name, call := range calls {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started
+			cancel()
+		}()
+		done := make(chan error, 1)
+		go func() { done <- call(ctx) }()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%s = %v, want context.Canceled", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s did not return after ctx was cancelled", name)
+		}
+		cancel()
+	}
+}
+
+type ctxKey struct{}
+
+// ctxCache records the ctxKey value of the context passed to each cache call.
+type ctxCache struct {
+	mu   sync.Mutex
+	seen []string
+	data map[string]string
+}
+
+func (c *ctxCache) record(ctx context.Context, call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen = append(c.seen, fmt.Sprintf("%s %v", call, ctx.Value(ctxKey{})))
+}
+
+func (c *ctxCache) Get(ctx context.Context, key string) (string, error) {
+	c.record(ctx, "get")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data[key], nil
+}
+
+func (c *ctxCache) Set(ctx context.Context, key, value string, _ time.Duration) error {
+	c.record(ctx, "set")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+	return nil
+}
+
+func (c *ctxCache) Delete(ctx context.Context, keys ...string) error {
+	c.record(ctx, "delete")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range keys {
+		delete(c.data, k)
+	}
+	return nil
+}
+
+func TestCtxReachesCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"$id":"d1","title":"Hello"}`)
+	}))
+	defer srv.Close()
+
+	c := &ctxCache{data: map[string]string{}}
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")).WithCache(c, time.Minute)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	if _, err := db.GetDocumentCtx(ctx, "db", "c", "d1"); err != nil {
+		t.Fatalf("GetDocumentCtx: %v", err)
+	}
+	if err := db.DeleteDocumentCtx(ctx, "db", "c", "d1"); err != nil {
+		t.Fatalf("DeleteDocumentCtx: %v", err)
+	}
+	want := []string{"get request-1", "set request-1", "delete request-1", "get request-1"}
+	if !slices.Equal(c.seen, want) {
+		t.Fatalf("cache calls = %q, want %q", c.seen, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -24,6 +25,39 @@ type DatabaseService struct {
 	Client   *AppwriteClient
 	Cache    cache.Cache
 	CacheTTL time.Duration
+	// ListOptions controls how ListDocuments pages through results.
+	ListOptions ListOptions
+	// VersionAttribute names the integer attribute UpdateDocumentIfUnchanged uses to
+	// detect concurrent changes; empty means $updatedAt.
+	VersionAttribute string
+}
+
+// ListOptions controls how ListDocuments pages through a collection. Pages are fetched
+// concurrently but always returned in server order.
+type ListOptions struct {
+	// PageSize is the number of documents per request; 0 means 800.
+	PageSize int
+	// Concurrency is the number of pages fetched in parallel; 0 means 5.
+	Concurrency int
+	// HonorLimitOffset makes limit and offset queries select a window of the results
+	// instead of being ignored.
+	HonorLimitOffset bool
+}
+
+const defaultListConcurrency = 5
+
+func (o ListOptions) pageSize() int {
+	if o.PageSize > 0 {
+		return o.PageSize
+	}
+	return defaultPageSize
+}
+
+func (o ListOptions) concurrency() int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return defaultListConcurrency
 }
 
 // Database represents an Appwrite database.
@@ -47,18 +81,30 @@ type Collection struct {
 // Document represents an Appwrite document.
 type Document struct {
 	ID          string                 `json:"$id"`
+	Sequence    int64                  `json:"$sequence"`
 	Collection  string                 `json:"$collectionId"`
 	Database    string                 `json:"$databaseId"`
+	CreatedAt   time.Time              `json:"$createdAt"`
+	UpdatedAt   time.Time              `json:"$updatedAt"`
 	Permissions []string               `json:"$permissions"`
 	Data        map[string]interface{} `json:"-"`
 }
 
 func (d Document) MarshalJSON() ([]byte, error) {
-	out := make(map[string]interface{}, len(d.Data)+5)
+	out := make(map[string]interface{}, len(d.Data)+8)
 	out["$id"] = d.ID
 	out["$collectionId"] = d.Collection
 	out["$databaseId"] = d.Database
 	out["$permissions"] = d.Permissions
+	if d.Sequence != 0 {
+		out["$sequence"] = d.Sequence
+	}
+	if !d.CreatedAt.IsZero() {
+		out["$createdAt"] = formatTimestamp(d.CreatedAt)
+	}
+	if !d.UpdatedAt.IsZero() {
+		out["$updatedAt"] = formatTimestamp(d.UpdatedAt)
+	}
 	if d.Data != nil {
 		for k, v := range d.Data {
 			out[k] = v
@@ -73,6 +119,11 @@ type Attribute struct {
 	Type     string `json:"type"`
 	Required bool   `json:"required"`
 	Array    bool   `json:"array"`
+	// Status is "available" once the server finished provisioning the attribute;
+	// see WaitForAttribute.
+	Status string `json:"status"`
+	// Error explains a "failed" or "stuck" status.
+	Error string `json:"error"`
 }
 
 // Permission constants
@@ -94,6 +145,12 @@ func (db *DatabaseService) WithCache(c cache.Cache, ttl time.Duration) *Database
 	return db
 }
 
+// WithListOptions configures how ListDocuments pages through results.
+func (db *DatabaseService) WithListOptions(opts ListOptions) *DatabaseService {
+	db.ListOptions = opts
+	return db
+}
+
 func (db *DatabaseService) cacheEnabled() bool {
 	return db != nil && db.Cache != nil && db.CacheTTL > 0
 }
@@ -102,12 +159,12 @@ func (db *DatabaseService) documentCacheKey(databaseID, collectionID, documentID
 	return fmt.Sprintf("doc:%s:%s:%s", databaseID, collectionID, documentID)
 }
 
-func (db *DatabaseService) invalidateDocumentCache(databaseID, collectionID, documentID string) {
+func (db *DatabaseService) invalidateDocumentCache(ctx context.Context, databaseID, collectionID, documentID string) {
 	if !db.cacheEnabled() {
 		return
 	}
-	_ = db.Cache.Delete(context.Background(), db.documentCacheKey(databaseID, collectionID, documentID))
-	db.invalidateCollectionCache(databaseID, collectionID)
+	_ = db.Cache.Delete(ctx, db.documentCacheKey(databaseID, collectionID, documentID))
+	db.invalidateCollectionCache(ctx, databaseID, collectionID)
 }
 
 func (db *DatabaseService) collectionCacheIndexKey(databaseID, collectionID string) string {
@@ -130,15 +187,22 @@ func (db *DatabaseService) listCacheKey(databaseID, collectionID string, queries
 	return fmt.Sprintf("list:%s", db.queryHash(databaseID, collectionID, queries))
 }
 
+func (db *DatabaseService) windowListCacheKey(databaseID, collectionID string, queries []string) string {
+	return fmt.Sprintf("listw:%s", db.queryHash(databaseID, collectionID, queries))
+}
+
 func (db *DatabaseService) countCacheKey(databaseID, collectionID string, queries []string) string {
 	return fmt.Sprintf("count:%s", db.queryHash(databaseID, collectionID, queries))
 }
 
-func (db *DatabaseService) trackCollectionCacheKey(databaseID, collectionID, cacheKey string) {
+func (db *DatabaseService) exactCountCacheKey(databaseID, collectionID string, queries []string) string {
+	return fmt.Sprintf("countx:%s", db.queryHash(databaseID, collectionID, queries))
+}
+
+func (db *DatabaseService) trackCollectionCacheKey(ctx context.Context, databaseID, collectionID, cacheKey string) {
 	if !db.cacheEnabled() {
 		return
 	}
-	ctx := context.Background()
 	indexKey := db.collectionCacheIndexKey(databaseID, collectionID)
 	existing, err := db.Cache.Get(ctx, indexKey)
 	if err != nil {
@@ -161,11 +225,10 @@ func (db *DatabaseService) trackCollectionCacheKey(databaseID, collectionID, cac
 	_ = db.Cache.Set(ctx, indexKey, indexValue, db.CacheTTL)
 }
 
-func (db *DatabaseService) invalidateCollectionCache(databaseID, collectionID string) {
+func (db *DatabaseService) invalidateCollectionCache(ctx context.Context, databaseID, collectionID string) {
 	if !db.cacheEnabled() {
 		return
 	}
-	ctx := context.Background()
 	indexKey := db.collectionCacheIndexKey(databaseID, collectionID)
 	existing, err := db.Cache.Get(ctx, indexKey)
 	if err != nil || existing == "" {
@@ -183,7 +246,13 @@ func (db *DatabaseService) invalidateCollectionCache(databaseID, collectionID st
 
 // ListDatabases retrieves a list of databases.
 func (db *DatabaseService) ListDatabases() ([]*Database, error) {
-	respBody, err := db.Client.sendRequest("GET", "/databases", nil)
+	return db.ListDatabasesCtx(context.Background())
+}
+
+// ListDatabasesCtx is like ListDatabases but uses ctx for the request.
+func (db *DatabaseService) ListDatabasesCtx(ctx context.Context) ([]*Database, error) {
+	op := newOperation("databases", "list")
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", "/databases", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -202,13 +271,19 @@ func (db *DatabaseService) ListDatabases() ([]*Database, error) {
 
 // CreateDatabase creates a new database.
 func (db *DatabaseService) CreateDatabase(databaseID, name string, enabled bool) (*Database, error) {
+	return db.CreateDatabaseCtx(context.Background(), databaseID, name, enabled)
+}
+
+// CreateDatabaseCtx is like CreateDatabase but uses ctx for the request.
+func (db *DatabaseService) CreateDatabaseCtx(ctx context.Context, databaseID, name string, enabled bool) (*Database, error) {
 	payload := map[string]interface{}{
 		"databaseId": databaseID,
 		"name":       name,
 		"enabled":    enabled,
 	}
 
-	respBody, err := db.Client.sendRequest("POST", "/databases", payload)
+	op := newOperation("databases", "create", "databaseId", databaseID)
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", "/databases", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -224,8 +299,14 @@ func (db *DatabaseService) CreateDatabase(databaseID, name string, enabled bool)
 
 // GetDatabase retrieves a database by its ID.
 func (db *DatabaseService) GetDatabase(databaseID string) (*Database, error) {
+	return db.GetDatabaseCtx(context.Background(), databaseID)
+}
+
+// GetDatabaseCtx is like GetDatabase but uses ctx for the request.
+func (db *DatabaseService) GetDatabaseCtx(ctx context.Context, databaseID string) (*Database, error) {
 	path := fmt.Sprintf("/databases/%s", databaseID)
-	respBody, err := db.Client.sendRequest("GET", path, nil)
+	op := newOperation("databases", "get", "databaseId", databaseID)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -241,13 +322,19 @@ func (db *DatabaseService) GetDatabase(databaseID string) (*Database, error) {
 
 // UpdateDatabase updates a database.
 func (db *DatabaseService) UpdateDatabase(databaseID, name string, enabled bool) (*Database, error) {
+	return db.UpdateDatabaseCtx(context.Background(), databaseID, name, enabled)
+}
+
+// UpdateDatabaseCtx is like UpdateDatabase but uses ctx for the request.
+func (db *DatabaseService) UpdateDatabaseCtx(ctx context.Context, databaseID, name string, enabled bool) (*Database, error) {
 	payload := map[string]interface{}{
 		"name":    name,
 		"enabled": enabled,
 	}
 
 	path := fmt.Sprintf("/databases/%s", databaseID)
-	respBody, err := db.Client.sendRequest("PUT", path, payload)
+	op := newOperation("databases", "update", "databaseId", databaseID)
+	respBody, err := db.Client.sendRequest(ctx, op, "PUT", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -263,15 +350,27 @@ func (db *DatabaseService) UpdateDatabase(databaseID, name string, enabled bool)
 
 // DeleteDatabase deletes a database.
 func (db *DatabaseService) DeleteDatabase(databaseID string) error {
+	return db.DeleteDatabaseCtx(context.Background(), databaseID)
+}
+
+// DeleteDatabaseCtx is like DeleteDatabase but uses ctx for the request.
+func (db *DatabaseService) DeleteDatabaseCtx(ctx context.Context, databaseID string) error {
 	path := fmt.Sprintf("/databases/%s", databaseID)
-	_, err := db.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("databases", "delete", "databaseId", databaseID)
+	_, err := db.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	return err
 }
 
 // ListCollections retrieves a list of collections in a database.
 func (db *DatabaseService) ListCollections(databaseID string) ([]*Collection, error) {
+	return db.ListCollectionsCtx(context.Background(), databaseID)
+}
+
+// ListCollectionsCtx is like ListCollections but uses ctx for the request.
+func (db *DatabaseService) ListCollectionsCtx(ctx context.Context, databaseID string) ([]*Collection, error) {
 	path := fmt.Sprintf("/databases/%s/collections", databaseID)
-	respBody, err := db.Client.sendRequest("GET", path, nil)
+	op := newOperation("databases", "listCollections", "databaseId", databaseID)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -290,6 +389,11 @@ func (db *DatabaseService) ListCollections(databaseID string) ([]*Collection, er
 
 // CreateCollection creates a new collection.
 func (db *DatabaseService) CreateCollection(databaseID, collectionID, name string, permissions []string, documentSecurity, enabled bool) (*Collection, error) {
+	return db.CreateCollectionCtx(context.Background(), databaseID, collectionID, name, permissions, documentSecurity, enabled)
+}
+
+// CreateCollectionCtx is like CreateCollection but uses ctx for the request.
+func (db *DatabaseService) CreateCollectionCtx(ctx context.Context, databaseID, collectionID, name string, permissions []string, documentSecurity, enabled bool) (*Collection, error) {
 	payload := map[string]interface{}{
 		"collectionId":     collectionID,
 		"name":             name,
@@ -299,7 +403,8 @@ func (db *DatabaseService) CreateCollection(databaseID, collectionID, name strin
 	}
 
 	path := fmt.Sprintf("/databases/%s/collections", databaseID)
-	respBody, err := db.Client.sendRequest("POST", path, payload)
+	op := newOperation("databases", "createCollection", "databaseId", databaseID, "collectionId", collectionID)
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -315,8 +420,14 @@ func (db *DatabaseService) CreateCollection(databaseID, collectionID, name strin
 
 // GetCollection retrieves a collection by its ID.
 func (db *DatabaseService) GetCollection(databaseID, collectionID string) (*Collection, error) {
+	return db.GetCollectionCtx(context.Background(), databaseID, collectionID)
+}
+
+// GetCollectionCtx is like GetCollection but uses ctx for the request.
+func (db *DatabaseService) GetCollectionCtx(ctx context.Context, databaseID, collectionID string) (*Collection, error) {
 	path := fmt.Sprintf("/databases/%s/collections/%s", databaseID, collectionID)
-	respBody, err := db.Client.sendRequest("GET", path, nil)
+	op := newOperation("databases", "getCollection", "databaseId", databaseID, "collectionId", collectionID)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -332,6 +443,11 @@ func (db *DatabaseService) GetCollection(databaseID, collectionID string) (*Coll
 
 // UpdateCollection updates a collection.
 func (db *DatabaseService) UpdateCollection(databaseID, collectionID, name string, permissions []string, documentSecurity, enabled bool) (*Collection, error) {
+	return db.UpdateCollectionCtx(context.Background(), databaseID, collectionID, name, permissions, documentSecurity, enabled)
+}
+
+// UpdateCollectionCtx is like UpdateCollection but uses ctx for the request.
+func (db *DatabaseService) UpdateCollectionCtx(ctx context.Context, databaseID, collectionID, name string, permissions []string, documentSecurity, enabled bool) (*Collection, error) {
 	payload := map[string]interface{}{
 		"name":             name,
 		"permissions":      permissions,
@@ -340,7 +456,8 @@ func (db *DatabaseService) UpdateCollection(databaseID, collectionID, name strin
 	}
 
 	path := fmt.Sprintf("/databases/%s/collections/%s", databaseID, collectionID)
-	respBody, err := db.Client.sendRequest("PUT", path, payload)
+	op := newOperation("databases", "updateCollection", "databaseId", databaseID, "collectionId", collectionID)
+	respBody, err := db.Client.sendRequest(ctx, op, "PUT", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -356,13 +473,24 @@ func (db *DatabaseService) UpdateCollection(databaseID, collectionID, name strin
 
 // DeleteCollection deletes a collection.
 func (db *DatabaseService) DeleteCollection(databaseID, collectionID string) error {
+	return db.DeleteCollectionCtx(context.Background(), databaseID, collectionID)
+}
+
+// DeleteCollectionCtx is like DeleteCollection but uses ctx for the request.
+func (db *DatabaseService) DeleteCollectionCtx(ctx context.Context, databaseID, collectionID string) error {
 	path := fmt.Sprintf("/databases/%s/collections/%s", databaseID, collectionID)
-	_, err := db.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("databases", "deleteCollection", "databaseId", databaseID, "collectionId", collectionID)
+	_, err := db.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	return err
 }
 
 // CreateDocument creates a new document.
 func (db *DatabaseService) CreateDocument(databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
+	return db.CreateDocumentCtx(context.Background(), databaseID, collectionID, documentID, data, permissions)
+}
+
+// CreateDocumentCtx is like CreateDocument but uses ctx for the request and cache calls.
+func (db *DatabaseService) CreateDocumentCtx(ctx context.Context, databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
 	payload := map[string]interface{}{
 		"documentId":  documentID,
 		"data":        data,
@@ -370,7 +498,8 @@ func (db *DatabaseService) CreateDocument(databaseID, collectionID, documentID s
 	}
 
 	path := fmt.Sprintf("/databases/%s/collections/%s/documents", databaseID, collectionID)
-	respBody, err := db.Client.sendRequest("POST", path, payload)
+	op := newOperation("databases", "createDocument", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -381,7 +510,7 @@ func (db *DatabaseService) CreateDocument(databaseID, collectionID, documentID s
 		return nil, err
 	}
 
-	db.invalidateDocumentCache(databaseID, collectionID, documentID)
+	db.invalidateDocumentCache(ctx, databaseID, collectionID, documentID)
 
 	return &document, nil
 }
@@ -391,6 +520,9 @@ func (d *Document) UnmarshalJSON(b []byte) error {
 	type Alias Document
 	aux := &struct {
 		*Alias
+		Sequence  interface{} `json:"$sequence"`
+		CreatedAt string      `json:"$createdAt"`
+		UpdatedAt string      `json:"$updatedAt"`
 	}{
 		Alias: (*Alias)(d),
 	}
@@ -400,6 +532,15 @@ func (d *Document) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	var err error
+	d.Sequence = parseSequence(aux.Sequence)
+	if d.CreatedAt, err = parseTimestamp(aux.CreatedAt); err != nil {
+		return err
+	}
+	if d.UpdatedAt, err = parseTimestamp(aux.UpdatedAt); err != nil {
+		return err
+	}
+
 	// Декодируем весь JSON в карту
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
@@ -408,6 +549,7 @@ func (d *Document) UnmarshalJSON(b []byte) error {
 
 	// Удаляем системные поля из карты и сохраняем в Data
 	delete(raw, "$id")
+	delete(raw, "$sequence")
 	delete(raw, "$createdAt")
 	delete(raw, "$updatedAt")
 	delete(raw, "$permissions")
@@ -420,18 +562,26 @@ func (d *Document) UnmarshalJSON(b []byte) error {
 
 // GetDocument retrieves a document by its ID.
 func (db *DatabaseService) GetDocument(databaseID, collectionID, documentID string) (*Document, error) {
+	return db.GetDocumentCtx(context.Background(), databaseID, collectionID, documentID)
+}
+
+// GetDocumentCtx is like GetDocument but uses ctx for the request and cache calls.
+func (db *DatabaseService) GetDocumentCtx(ctx context.Context, databaseID, collectionID, documentID string) (*Document, error) {
+	op := newOperation("databases", "getDocument", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
 	cacheKey := db.documentCacheKey(databaseID, collectionID, documentID)
 	if db.cacheEnabled() {
-		if cached, err := db.Cache.Get(context.Background(), cacheKey); err == nil && cached != "" {
+		if cached, err := db.Cache.Get(ctx, cacheKey); err == nil && cached != "" {
 			var cachedDocument Document
 			if err := cachedDocument.UnmarshalJSON([]byte(cached)); err == nil {
+				db.Client.cacheLookup(ctx, op, true)
 				return &cachedDocument, nil
 			}
 		}
+		db.Client.cacheLookup(ctx, op, false)
 	}
 
 	path := fmt.Sprintf("/databases/%s/collections/%s/documents/%s", databaseID, collectionID, documentID)
-	respBody, err := db.Client.sendRequest("GET", path, nil)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -443,7 +593,7 @@ func (db *DatabaseService) GetDocument(databaseID, collectionID, documentID stri
 	}
 
 	if db.cacheEnabled() {
-		_ = db.Cache.Set(context.Background(), cacheKey, string(respBody), db.CacheTTL)
+		_ = db.Cache.Set(ctx, cacheKey, string(respBody), db.CacheTTL)
 	}
 
 	return &document, nil
@@ -451,13 +601,19 @@ func (db *DatabaseService) GetDocument(databaseID, collectionID, documentID stri
 
 // UpdateDocument updates a document.
 func (db *DatabaseService) UpdateDocument(databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
+	return db.UpdateDocumentCtx(context.Background(), databaseID, collectionID, documentID, data, permissions)
+}
+
+// UpdateDocumentCtx is like UpdateDocument but uses ctx for the request and cache calls.
+func (db *DatabaseService) UpdateDocumentCtx(ctx context.Context, databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
 	payload := map[string]interface{}{
 		"data":        data,
 		"permissions": permissions,
 	}
 
 	path := fmt.Sprintf("/databases/%s/collections/%s/documents/%s", databaseID, collectionID, documentID)
-	respBody, err := db.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("databases", "updateDocument", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
+	respBody, err := db.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -468,17 +624,121 @@ func (db *DatabaseService) UpdateDocument(databaseID, collectionID, documentID s
 		return nil, err
 	}
 
-	db.invalidateDocumentCache(databaseID, collectionID, documentID)
+	db.invalidateDocumentCache(ctx, databaseID, collectionID, documentID)
+
+	return &document, nil
+}
+
+// UpsertDocument creates the document or replaces the data and permissions of the
+// existing one. Servers before 1.7 lack the endpoint, so a create is tried first and
+// followed by an update when the document exists.
+func (db *DatabaseService) UpsertDocument(databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
+	return db.UpsertDocumentCtx(context.Background(), databaseID, collectionID, documentID, data, permissions)
+}
+
+// UpsertDocumentCtx is like UpsertDocument but uses ctx for the request and cache calls.
+func (db *DatabaseService) UpsertDocumentCtx(ctx context.Context, databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) (*Document, error) {
+	if !db.Client.serverAtLeast(ctx, upsertMinVersion) {
+		return db.upsertDocumentFallback(ctx, databaseID, collectionID, documentID, data, permissions)
+	}
+
+	payload := map[string]interface{}{
+		"data":        data,
+		"permissions": permissions,
+	}
+
+	path := fmt.Sprintf("/databases/%s/collections/%s/documents/%s", databaseID, collectionID, documentID)
+	op := newOperation("databases", "upsertDocument", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
+	respBody, err := db.Client.sendRequest(ctx, op, "PUT", path, payload)
+	if isUnsupportedRoute(err) {
+		return db.upsertDocumentFallback(ctx, databaseID, collectionID, documentID, data, permissions)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	err = json.Unmarshal(respBody, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	db.invalidateDocumentCache(ctx, databaseID, collectionID, documentID)
+
+	return &document, nil
+}
+
+// IncrementDocumentAttribute atomically adds value to a numeric attribute. A non-nil
+// max makes the server reject increments that would exceed it. It requires Appwrite
+// 1.7 or later and returns an error wrapping errors.ErrUnsupported on older servers.
+func (db *DatabaseService) IncrementDocumentAttribute(databaseID, collectionID, documentID, attribute string, value float64, max *float64) (*Document, error) {
+	return db.IncrementDocumentAttributeCtx(context.Background(), databaseID, collectionID, documentID, attribute, value, max)
+}
+
+// IncrementDocumentAttributeCtx is like IncrementDocumentAttribute but uses ctx for the request and cache calls.
+func (db *DatabaseService) IncrementDocumentAttributeCtx(ctx context.Context, databaseID, collectionID, documentID, attribute string, value float64, max *float64) (*Document, error) {
+	return db.adjustDocumentAttribute(ctx, databaseID, collectionID, documentID, attribute, "increment", value, "max", max)
+}
+
+// DecrementDocumentAttribute atomically subtracts value from a numeric attribute. A
+// non-nil min makes the server reject decrements that would go below it. It requires
+// Appwrite 1.7 or later and returns an error wrapping errors.ErrUnsupported on older
+// servers.
+func (db *DatabaseService) DecrementDocumentAttribute(databaseID, collectionID, documentID, attribute string, value float64, min *float64) (*Document, error) {
+	return db.DecrementDocumentAttributeCtx(context.Background(), databaseID, collectionID, documentID, attribute, value, min)
+}
+
+// DecrementDocumentAttributeCtx is like DecrementDocumentAttribute but uses ctx for the request and cache calls.
+func (db *DatabaseService) DecrementDocumentAttributeCtx(ctx context.Context, databaseID, collectionID, documentID, attribute string, value float64, min *float64) (*Document, error) {
+	return db.adjustDocumentAttribute(ctx, databaseID, collectionID, documentID, attribute, "decrement", value, "min", min)
+}
+
+func (db *DatabaseService) adjustDocumentAttribute(ctx context.Context, databaseID, collectionID, documentID, attribute, action string, value float64, boundName string, bound *float64) (*Document, error) {
+	unsupported := fmt.Errorf("gowrite: %s of document attributes requires appwrite %s: %w", action, upsertMinVersion, errors.ErrUnsupported)
+	if !db.Client.serverAtLeast(ctx, upsertMinVersion) {
+		return nil, unsupported
+	}
+
+	payload := map[string]interface{}{
+		"value": value,
+	}
+	if bound != nil {
+		payload[boundName] = *bound
+	}
+
+	path := fmt.Sprintf("/databases/%s/collections/%s/documents/%s/%s/%s", databaseID, collectionID, documentID, attribute, action)
+	op := newOperation("databases", action+"DocumentAttribute", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID, "attribute", attribute)
+	respBody, err := db.Client.sendRequest(ctx, op, "PATCH", path, payload)
+	if isUnsupportedRoute(err) {
+		return nil, unsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	err = json.Unmarshal(respBody, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	db.invalidateDocumentCache(ctx, databaseID, collectionID, documentID)
 
 	return &document, nil
 }
 
 // DeleteDocument deletes a document.
 func (db *DatabaseService) DeleteDocument(databaseID, collectionID, documentID string) error {
+	return db.DeleteDocumentCtx(context.Background(), databaseID, collectionID, documentID)
+}
+
+// DeleteDocumentCtx is like DeleteDocument but uses ctx for the request and cache calls.
+func (db *DatabaseService) DeleteDocumentCtx(ctx context.Context, databaseID, collectionID, documentID string) error {
 	path := fmt.Sprintf("/databases/%s/collections/%s/documents/%s", databaseID, collectionID, documentID)
-	_, err := db.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("databases", "deleteDocument", "databaseId", databaseID, "collectionId", collectionID, "documentId", documentID)
+	_, err := db.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	if err == nil {
-		db.invalidateDocumentCache(databaseID, collectionID, documentID)
+		db.invalidateDocumentCache(ctx, databaseID, collectionID, documentID)
 	}
 	return err
 }
@@ -486,20 +746,37 @@ func (db *DatabaseService) DeleteDocument(databaseID, collectionID, documentID s
 // ListDocuments получает список всех документов в коллекции, обрабатывая пагинацию для получения
 // всех документов, превышающих лимит в 5000 за один запрос.
 func (db *DatabaseService) ListDocuments(databaseID, collectionID string, queries []string) ([]*Document, error) {
-	const (
-		maxLimit    = 800
-		concurrency = 5
-	)
+	return db.ListDocumentsCtx(context.Background(), databaseID, collectionID, queries)
+}
+
+// ListDocumentsCtx is like ListDocuments but uses ctx for the request and cache calls.
+func (db *DatabaseService) ListDocumentsCtx(ctx context.Context, databaseID, collectionID string, queries []string) ([]*Document, error) {
+	return db.ListDocumentsWithOptions(ctx, databaseID, collectionID, queries, db.ListOptions)
+}
+
+// ListDocumentsWithOptions is like ListDocumentsCtx but uses opts instead of the
+// service's ListOptions.
+func (db *DatabaseService) ListDocumentsWithOptions(ctx context.Context, databaseID, collectionID string, queries []string, opts ListOptions) (_ []*Document, err error) {
+	pageSize, concurrency := opts.pageSize(), opts.concurrency()
+
+	op := newOperation("databases", "listDocuments", "databaseId", databaseID, "collectionId", collectionID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
 
 	cacheKey := ""
 	if db.cacheEnabled() {
 		cacheKey = db.listCacheKey(databaseID, collectionID, queries)
-		if cached, err := db.Cache.Get(context.Background(), cacheKey); err == nil && cached != "" {
+		if opts.HonorLimitOffset {
+			cacheKey = db.windowListCacheKey(databaseID, collectionID, queries)
+		}
+		if cached, err := db.Cache.Get(ctx, cacheKey); err == nil && cached != "" {
 			var cachedDocs []*Document
 			if err := json.Unmarshal([]byte(cached), &cachedDocs); err == nil {
+				db.Client.cacheLookup(ctx, op, true)
 				return cachedDocs, nil
 			}
 		}
+		db.Client.cacheLookup(ctx, op, false)
 	}
 
 	// Предварительно фильтруем запросы, убирая limit и offset
@@ -512,73 +789,91 @@ func (db *DatabaseService) ListDocuments(databaseID, collectionID string, querie
 		}
 	}
 
+	// Без HonorLimitOffset читаем всю выборку; иначе только окно [start, start+limit)
+	start, limit := 0, -1
+	if opts.HonorLimitOffset {
+		pq := splitPagingQueries(queries)
+		start, limit = pq.offset, pq.limit
+	}
+	maxPages := -1
+	if limit >= 0 {
+		maxPages = (limit + pageSize - 1) / pageSize
+	}
+
 	type pageResult struct {
 		docs    []*Document
 		err     error
 		hasMore bool
 	}
 
-	fetchPage := func(off int) pageResult {
+	fetchPage := func(page int) pageResult {
+		off := start + page*pageSize
+		size := pageSize
+		if limit >= 0 && limit-page*pageSize < size {
+			size = limit - page*pageSize
+		}
+
 		q := url.Values{}
 		for _, qs := range baseQueries {
 			q.Add("queries[]", qs)
 		}
-		q.Add("queries[]", query.Limit(maxLimit))
+		q.Add("queries[]", query.Limit(int64(size)))
 		q.Add("queries[]", query.Offset(off))
 
 		path := fmt.Sprintf("/databases/%s/collections/%s/documents?%s", databaseID, collectionID, q.Encode())
 
-		respBody, err := db.Client.sendRequest("GET", path, nil)
+		pageOp := newOperation("databases", "listDocuments", "databaseId", databaseID, "collectionId", collectionID, "offset", strconv.Itoa(off))
+		respBody, err := db.Client.sendRequest(ctx, pageOp, "GET", path, nil)
 		if err != nil {
 			return pageResult{nil, err, false}
 		}
 
-		var result struct {
-			Documents []*Document `json:"documents"`
-		}
-
+		var result documentList
 		if err = _json.Unmarshal(respBody, &result); err != nil {
 			return pageResult{nil, err, false}
 		}
 
-		hasMore := len(result.Documents) == maxLimit
+		hasMore := len(result.Documents) == size
 		return pageResult{result.Documents, nil, hasMore}
 	}
 
+	// Страницы складываются по номеру, чтобы сохранить порядок сервера
 	var (
-		allDocs []*Document
-		off     int
-		mu      sync.Mutex
-		wg      sync.WaitGroup
-		errOnce sync.Once
-		retErr  error
+		pages  = make(map[int][]*Document)
+		next   int
+		last   = -1 // номер последней страницы, когда он известен
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		retErr error
 	)
 
 	worker := func() {
 		defer wg.Done()
 		for {
 			mu.Lock()
-			myOff := off
-			off += maxLimit
-			mu.Unlock()
-
-			res := fetchPage(myOff)
-			if res.err != nil {
-				errOnce.Do(func() { retErr = res.err })
+			if retErr != nil || (last >= 0 && next > last) || (maxPages >= 0 && next >= maxPages) {
+				mu.Unlock()
 				return
 			}
+			page := next
+			next++
+			mu.Unlock()
 
-			if len(res.docs) == 0 {
-				return
-			}
+			res := fetchPage(page)
 
 			mu.Lock()
-			allDocs = append(allDocs, res.docs...)
-			mu.Unlock()
-
-			if !res.hasMore {
+			if res.err != nil {
+				if retErr == nil {
+					retErr = res.err
+				}
+				mu.Unlock()
 				return
 			}
+			pages[page] = res.docs
+			if !res.hasMore && (last < 0 || page < last) {
+				last = page
+			}
+			mu.Unlock()
 		}
 	}
 
@@ -592,86 +887,98 @@ func (db *DatabaseService) ListDocuments(databaseID, collectionID string, querie
 		return nil, retErr
 	}
 
+	var allDocs []*Document
+	for page := 0; page < next && (last < 0 || page <= last); page++ {
+		allDocs = append(allDocs, pages[page]...)
+	}
+
 	if db.cacheEnabled() {
 		docs := allDocs
 		if docs == nil {
 			docs = []*Document{}
 		}
 		if data, err := json.Marshal(docs); err == nil {
-			if err := db.Cache.Set(context.Background(), cacheKey, string(data), db.CacheTTL); err == nil {
-				db.trackCollectionCacheKey(databaseID, collectionID, cacheKey)
+			if err := db.Cache.Set(ctx, cacheKey, string(data), db.CacheTTL); err == nil {
+				db.trackCollectionCacheKey(ctx, databaseID, collectionID, cacheKey)
 			}
 		}
 	}
 	return allDocs, nil
 }
 
+// CountDocuments returns the number of documents matching queries as reported by the
+// server's total, without downloading the documents. Appwrite stops counting at a cap
+// (5000 by default), so use CountDocumentsExact when larger counts must be exact.
 func (db *DatabaseService) CountDocuments(databaseID, collectionID string, queries []string) (int, error) {
-	const maxLimit = 800
-
-	cacheKey := ""
-	if db.cacheEnabled() {
-		cacheKey = db.countCacheKey(databaseID, collectionID, queries)
-		if cached, err := db.Cache.Get(context.Background(), cacheKey); err == nil && cached != "" {
-			if v, err := strconv.Atoi(cached); err == nil {
-				return v, nil
-			}
-		}
-	}
-
-	// Предварительно фильтруем запросы, убирая limit и offset
-	baseQueries := make([]string, 0, len(queries))
-	for _, q := range queries {
-		var pq query.QueryOptions
-		if err := _json.UnmarshalFromString(q, &pq); err == nil &&
-			pq.Method != "limit" && pq.Method != "offset" {
-			baseQueries = append(baseQueries, q)
-		}
-	}
-
-	offset := 0
-	totalCount := 0
-
-	for {
-		q := url.Values{}
-		for _, queryStr := range baseQueries {
-			q.Add("queries[]", queryStr)
-		}
-		q.Add("queries[]", query.Limit(maxLimit))
-		q.Add("queries[]", query.Offset(offset))
-
-		path := fmt.Sprintf("/databases/%s/collections/%s/documents?%s", databaseID, collectionID, q.Encode())
+	return db.CountDocumentsCtx(context.Background(), databaseID, collectionID, queries)
+}
 
-		respBody, err := db.Client.sendRequest("GET", path, nil)
+// CountDocumentsCtx is like CountDocuments but uses ctx for the request and cache calls.
+func (db *DatabaseService) CountDocumentsCtx(ctx context.Context, databaseID, collectionID string, queries []string) (_ int, err error) {
+	op := newOperation("databases", "countDocuments", "databaseId", databaseID, "collectionId", collectionID)
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	return db.cachedCount(ctx, op, databaseID, collectionID, db.countCacheKey(databaseID, collectionID, queries), func() (int, error) {
+		q := splitPagingQueries(queries).base
+		q = append(q, query.Select([]string{"$id"}), query.Limit(1))
+		pageOp := newOperation("databases", "listDocuments", "databaseId", databaseID, "collectionId", collectionID)
+		page, err := db.listDocumentsPage(ctx, pageOp, databaseID, collectionID, q)
 		if err != nil {
 			return 0, err
 		}
+		return page.Total, nil
+	})
+}
 
-		var result struct {
-			Documents []*Document `json:"documents"`
-		}
+// CountDocumentsExact counts the documents matching queries one by one, paging through
+// their IDs only. It is slower than CountDocuments but not limited by the server's cap.
+func (db *DatabaseService) CountDocumentsExact(databaseID, collectionID string, queries []string) (int, error) {
+	return db.CountDocumentsExactCtx(context.Background(), databaseID, collectionID, queries)
+}
 
-		if err = _json.Unmarshal(respBody, &result); err != nil {
-			return 0, err
+// CountDocumentsExactCtx is like CountDocumentsExact but uses ctx for the request and cache calls.
+func (db *DatabaseService) CountDocumentsExactCtx(ctx context.Context, databaseID, collectionID string, queries []string) (_ int, err error) {
+	op := newOperation("databases", "countDocuments", "databaseId", databaseID, "collectionId", collectionID, "mode", "exact")
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	return db.cachedCount(ctx, op, databaseID, collectionID, db.exactCountCacheKey(databaseID, collectionID, queries), func() (int, error) {
+		q := append(splitPagingQueries(queries).base, query.Select([]string{"$id"}))
+		count := 0
+		for _, err := range db.IterateDocuments(ctx, databaseID, collectionID, q) {
+			if err != nil {
+				return 0, err
+			}
+			count++
 		}
+		return count, nil
+	})
+}
 
-		count := len(result.Documents)
-		totalCount += count
-
-		if count < maxLimit {
-			break
+// cachedCount returns the cached count under cacheKey or computes and caches it.
+func (db *DatabaseService) cachedCount(ctx context.Context, op Operation, databaseID, collectionID, cacheKey string, count func() (int, error)) (int, error) {
+	if db.cacheEnabled() {
+		if cached, err := db.Cache.Get(ctx, cacheKey); err == nil && cached != "" {
+			if v, err := strconv.Atoi(cached); err == nil {
+				db.Client.cacheLookup(ctx, op, true)
+				return v, nil
+			}
 		}
+		db.Client.cacheLookup(ctx, op, false)
+	}
 
-		offset += maxLimit
+	total, err := count()
+	if err != nil {
+		return 0, err
 	}
 
 	if db.cacheEnabled() {
-		if err := db.Cache.Set(context.Background(), cacheKey, strconv.Itoa(totalCount), db.CacheTTL); err == nil {
-			db.trackCollectionCacheKey(databaseID, collectionID, cacheKey)
+		if err := db.Cache.Set(ctx, cacheKey, strconv.Itoa(total), db.CacheTTL); err == nil {
+			db.trackCollectionCacheKey(ctx, databaseID, collectionID, cacheKey)
 		}
 	}
-
-	return totalCount, nil
+	return total, nil
 }
 
 // AttributeType defines allowed attribute types when creating attributes.
@@ -690,8 +997,14 @@ const (
 	AttributeURL          AttributeType = "url"
 )
 
-// CreateAttribute creates a new attribute for a collection.
+// CreateAttribute creates a new attribute for a collection. meta holds the
+// type-specific options; CreateTypedAttribute takes them as typed, validated fields.
 func (db *DatabaseService) CreateAttribute(databaseID, collectionID, key string, attrType AttributeType, required bool, defaultValue interface{}, array bool, meta map[string]interface{}) (*Attribute, error) {
+	return db.CreateAttributeCtx(context.Background(), databaseID, collectionID, key, attrType, required, defaultValue, array, meta)
+}
+
+// CreateAttributeCtx is like CreateAttribute but uses ctx for the request.
+func (db *DatabaseService) CreateAttributeCtx(ctx context.Context, databaseID, collectionID, key string, attrType AttributeType, required bool, defaultValue interface{}, array bool, meta map[string]interface{}) (*Attribute, error) {
 	payload := map[string]interface{}{
 		"key":      key,
 		"required": required,
@@ -703,7 +1016,8 @@ func (db *DatabaseService) CreateAttribute(databaseID, collectionID, key string,
 	}
 
 	path := fmt.Sprintf("/databases/%s/collections/%s/attributes/%s", databaseID, collectionID, attrType)
-	respBody, err := db.Client.sendRequest("POST", path, payload)
+	op := newOperation("databases", "createAttribute", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -716,10 +1030,15 @@ func (db *DatabaseService) CreateAttribute(databaseID, collectionID, key string,
 	return &attr, nil
 }
 
-// GetAttribute retrieves an attribute from a collection.
+// GetAttribute retrieves an attribute from a collection. GetTypedAttribute returns it
+// decoded into the model of its type.
 func (db *DatabaseService) GetAttribute(databaseID, collectionID, key string) (*Attribute, error) {
-	path := fmt.Sprintf("/databases/%s/collections/%s/attributes/%s", databaseID, collectionID, key)
-	respBody, err := db.Client.sendRequest("GET", path, nil)
+	return db.GetAttributeCtx(context.Background(), databaseID, collectionID, key)
+}
+
+// GetAttributeCtx is like GetAttribute but uses ctx for the request.
+func (db *DatabaseService) GetAttributeCtx(ctx context.Context, databaseID, collectionID, key string) (*Attribute, error) {
+	respBody, err := db.getAttribute(ctx, databaseID, collectionID, key)
 	if err != nil {
 		return nil, err
 	}
@@ -732,24 +1051,34 @@ func (db *DatabaseService) GetAttribute(databaseID, collectionID, key string) (*
 	return &attr, nil
 }
 
+func (db *DatabaseService) getAttribute(ctx context.Context, databaseID, collectionID, key string) ([]byte, error) {
+	path := fmt.Sprintf("/databases/%s/collections/%s/attributes/%s", databaseID, collectionID, key)
+	op := newOperation("databases", "getAttribute", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	return db.Client.sendRequest(ctx, op, "GET", path, nil)
+}
+
 // DeleteAttribute deletes an attribute from a collection.
 func (db *DatabaseService) DeleteAttribute(databaseID, collectionID, key string) error {
+	return db.DeleteAttributeCtx(context.Background(), databaseID, collectionID, key)
+}
+
+// DeleteAttributeCtx is like DeleteAttribute but uses ctx for the request.
+func (db *DatabaseService) DeleteAttributeCtx(ctx context.Context, databaseID, collectionID, key string) error {
 	path := fmt.Sprintf("/databases/%s/collections/%s/attributes/%s", databaseID, collectionID, key)
-	_, err := db.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("databases", "deleteAttribute", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	_, err := db.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	return err
 }
 
-// ListAttributes retrieves all attributes from a collection.
+// ListAttributes retrieves all attributes from a collection. ListTypedAttributes
+// returns them decoded into the models of their types.
 func (db *DatabaseService) ListAttributes(databaseID, collectionID string, queries []string) ([]*Attribute, error) {
-	q := url.Values{}
-	for _, qs := range queries {
-		q.Add("queries[]", qs)
-	}
-	path := fmt.Sprintf("/databases/%s/collections/%s/attributes", databaseID, collectionID)
-	if encoded := q.Encode(); encoded != "" {
-		path += "?" + encoded
-	}
-	respBody, err := db.Client.sendRequest("GET", path, nil)
+	return db.ListAttributesCtx(context.Background(), databaseID, collectionID, queries)
+}
+
+// ListAttributesCtx is like ListAttributes but uses ctx for the request.
+func (db *DatabaseService) ListAttributesCtx(ctx context.Context, databaseID, collectionID string, queries []string) ([]*Attribute, error) {
+	respBody, err := db.listAttributes(ctx, databaseID, collectionID, queries)
 	if err != nil {
 		return nil, err
 	}
@@ -764,8 +1093,26 @@ func (db *DatabaseService) ListAttributes(databaseID, collectionID string, queri
 	return result.Attributes, nil
 }
 
+func (db *DatabaseService) listAttributes(ctx context.Context, databaseID, collectionID string, queries []string) ([]byte, error) {
+	q := url.Values{}
+	for _, qs := range queries {
+		q.Add("queries[]", qs)
+	}
+	path := fmt.Sprintf("/databases/%s/collections/%s/attributes", databaseID, collectionID)
+	if encoded := q.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+	op := newOperation("databases", "listAttributes", "databaseId", databaseID, "collectionId", collectionID)
+	return db.Client.sendRequest(ctx, op, "GET", path, nil)
+}
+
 // UpdateAttribute updates an attribute of a given type.
 func (db *DatabaseService) UpdateAttribute(databaseID, collectionID, key string, attrType AttributeType, updates map[string]interface{}) (*Attribute, error) {
+	return db.UpdateAttributeCtx(context.Background(), databaseID, collectionID, key, attrType, updates)
+}
+
+// UpdateAttributeCtx is like UpdateAttribute but uses ctx for the request.
+func (db *DatabaseService) UpdateAttributeCtx(ctx context.Context, databaseID, collectionID, key string, attrType AttributeType, updates map[string]interface{}) (*Attribute, error) {
 	var path string
 	if attrType == AttributeRelationship {
 		path = fmt.Sprintf("/databases/%s/collections/%s/attributes/%s/relationship", databaseID, collectionID, key)
@@ -773,7 +1120,8 @@ func (db *DatabaseService) UpdateAttribute(databaseID, collectionID, key string,
 		path = fmt.Sprintf("/databases/%s/collections/%s/attributes/%s/%s", databaseID, collectionID, attrType, key)
 	}
 
-	respBody, err := db.Client.sendRequest("PATCH", path, updates)
+	op := newOperation("databases", "updateAttribute", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	respBody, err := db.Client.sendRequest(ctx, op, "PATCH", path, updates)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,550 @@
+package gowrite_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/query"
+)
+
+func TestDocumentSystemFieldsRoundTrip(t *testing.T) {
+	raw := `{"$id":"d1","$sequence":"7","$collectionId":"c","$databaseId":"db",` +
+		`"$createdAt":"2024-05-01T10:00:00.123+00:00","$updatedAt":"2024-05-02T11:30:00.000+00:00",` +
+		`"$permissions":["read(\"any\")"],"title":"hello"}`
+
+	var doc gowrite.Document
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	wantCreated := time.Date(2024, 5, 1, 10, 0, 0, 123e6, time.UTC)
+	if doc.Sequence != 7 || !doc.CreatedAt.Equal(wantCreated) || doc.UpdatedAt.IsZero() {
+		t.Fatalf("system fields not parsed: %+v", doc)
+	}
+	if len(doc.Data) != 1 || doc.Data["title"] != "hello" {
+		t.Fatalf("Data = %v", doc.Data)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var again gowrite.Document
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatalf("Unmarshal round trip: %v", err)
+	}
+	if again.Sequence != doc.Sequence || !again.CreatedAt.Equal(doc.CreatedAt) || !again.UpdatedAt.Equal(doc.UpdatedAt) {
+		t.Fatalf("round trip lost system fields: %+v", again)
+	}
+}
+
+func TestUserAndFileKeepDataKeys(t *testing.T) {
+	var user gowrite.User
+	if err := json.Unmarshal([]byte(`{"$id":"u1","$createdAt":"2024-05-01T10:00:00.000+00:00","name":"Ann","prefs":{"theme":"dark"}}`), &user); err != nil {
+		t.Fatalf("Unmarshal user: %v", err)
+	}
+	if user.Prefs["theme"] != "dark" || user.Data["prefs"] == nil || user.Data["$createdAt"] == nil || user.Data["name"] != nil {
+		t.Fatalf("user = %+v", user)
+	}
+
+	var file gowrite.File
+	if err := json.Unmarshal([]byte(`{"$id":"f1","$createdAt":"2024-05-01T10:00:00.000+00:00","name":"a.txt"}`), &file); err != nil {
+		t.Fatalf("Unmarshal file: %v", err)
+	}
+	if file.CreatedAt.IsZero() || file.Data["$createdAt"] == nil || file.Data["name"] != nil {
+		t.Fatalf("file = %+v", file)
+	}
+}
+
+// documentServer serves n documents with IDs d0000, d0001, ... honoring limit, offset
+// and cursorAfter queries, and records the queries of each request.
+func documentServer(t *testing.T, n int) (*httptest.Server, *[][]string) {
+	var (
+		mu       sync.Mutex
+		requests [][]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries := r.URL.Query()["queries[]"]
+		mu.Lock()
+		requests = append(requests, queries)
+		mu.Unlock()
+
+		start, limit := 0, 25
+		for _, q := range queries {
+			opts, err := query.Parse(q)
+			if err != nil || opts.Values == nil {
+				continue
+			}
+			switch v := (*opts.Values)[0]; opts.Method {
+			case "limit":
+				limit = int(v.(float64))
+			case "offset":
+				start += int(v.(float64))
+			case "cursorAfter":
+				fmt.Sscanf(v.(string), "d%d", &start)
+				start++
+			}
+		}
+		docs := []map[string]interface{}{}
+		for i := start; i < n && len(docs) < limit; i++ {
+			docs = append(docs, map[string]interface{}{"$id": fmt.Sprintf("d%04d", i)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": n, "documents": docs})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestIterateDocuments(t *testing.T) {
+	srv, requests := documentServer(t, 2000)
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+
+	var ids []string
+	for doc, err := range db.IterateDocuments(context.Background(), "db", "c", []string{query.Limit(1000)}) {
+		if err != nil {
+			t.Fatalf("IterateDocuments: %v", err)
+		}
+		ids = append(ids, doc.ID)
+	}
+	if len(ids) != 1000 || ids[999] != "d0999" {
+		t.Fatalf("got %d documents, last %q", len(ids), ids[len(ids)-1])
+	}
+	if len(*requests) != 2 || !slices.Contains((*requests)[1], query.CursorAfter("d0799")) {
+		t.Fatalf("requests = %v", *requests)
+	}
+
+	*requests = nil
+	n := 0
+	for _, err := range db.IterateDocuments(context.Background(), "db", "c", nil) {
+		if err != nil {
+			t.Fatalf("IterateDocuments: %v", err)
+		}
+		if n++; n == 5 {
+			break
+		}
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("early stop sent %d requests", len(*requests))
+	}
+}
+
+func TestCountDocuments(t *testing.T) {
+	srv, requests := documentServer(t, 2000)
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+
+	n, err := db.CountDocuments("db", "c", []string{query.Limit(10)})
+	if err != nil || n != 2000 {
+		t.Fatalf("CountDocuments = %d, %v", n, err)
+	}
+	if len(*requests) != 1 || !slices.Contains((*requests)[0], query.Limit(1)) || slices.Contains((*requests)[0], query.Limit(10)) {
+		t.Fatalf("requests = %v", *requests)
+	}
+
+	*requests = nil
+	n, err = db.CountDocumentsExact("db", "c", nil)
+	if err != nil || n != 2000 {
+		t.Fatalf("CountDocumentsExact = %d, %v", n, err)
+	}
+	if len(*requests) != 3 {
+		t.Fatalf("exact count sent %d requests", len(*requests))
+	}
+}
+
+func TestListDocumentsOptions(t *testing.T) {
+	srv, _ := documentServer(t, 95)
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")).
+		WithListOptions(gowrite.ListOptions{PageSize: 10, Concurrency: 4})
+
+	docs, err := db.ListDocuments("db", "c", []string{query.Limit(5)})
+	if err != nil || len(docs) != 95 {
+		t.Fatalf("ListDocuments = %d documents, %v", len(docs), err)
+	}
+	for i, doc := range docs {
+		if want := fmt.Sprintf("d%04d", i); doc.ID != want {
+			t.Fatalf("docs[%d] = %s, want %s", i, doc.ID, want)
+		}
+	}
+
+	opts := gowrite.ListOptions{PageSize: 10, Concurrency: 4, HonorLimitOffset: true}
+	docs, err = db.ListDocumentsWithOptions(context.Background(), "db", "c", []string{query.Offset(7), query.Limit(25)}, opts)
+	if err != nil || len(docs) != 25 || docs[0].ID != "d0007" || docs[24].ID != "d0031" {
+		t.Fatalf("ListDocumentsWithOptions = %d documents, %v", len(docs), err)
+	}
+}
+
+func TestIncrementDocumentAttribute(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/health/version" {
+			fmt.Fprint(w, `{"version":"1.7.0"}`)
+			return
+		}
+		if r.Method != "PATCH" || r.URL.Path != "/v1/databases/db/collections/c/documents/d1/views/increment" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"$id":"d1","views":3}`)
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	max := 10.0
+	doc, err := db.IncrementDocumentAttribute("db", "c", "d1", "views", 1, &max)
+	if err != nil || doc.Data["views"] != float64(3) {
+		t.Fatalf("IncrementDocumentAttribute = %+v, %v", doc, err)
+	}
+	if got["value"] != float64(1) || got["max"] != float64(10) {
+		t.Fatalf("payload = %v", got)
+	}
+
+	srv16 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version":"1.6.2"}`)
+	}))
+	defer srv16.Close()
+	db = gowrite.NewDatabases(gowrite.NewClient(srv16.URL, "p", "k"))
+	if _, err := db.DecrementDocumentAttribute("db", "c", "d1", "views", 1, nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("DecrementDocumentAttribute on 1.6 = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestUpdateDocumentIfUnchanged(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		version = 2
+		patches []map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.0"}`)
+		case r.Method == "GET":
+			fmt.Fprintf(w, `{"$id":"d1","$databaseId":"db","$collectionId":"c","rev":%d,"views":10}`, version)
+		case r.Method == "PATCH":
+			var body struct {
+				Data    map[string]interface{} `json:"data"`
+				Queries []string               `json:"queries"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			patches = append(patches, body.Data)
+			if !slices.Contains(body.Queries, query.Equal("rev", version)) {
+				fmt.Fprint(w, `{"total":0,"documents":[]}`)
+				return
+			}
+			version++
+			views, _ := json.Marshal(body.Data["views"])
+			fmt.Fprintf(w, `{"total":1,"documents":[{"$id":"d1","rev":%d,"views":%s}]}`, version, views)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")).WithVersionAttribute("rev")
+	stale := &gowrite.Document{ID: "d1", Database: "db", Collection: "c", Data: map[string]interface{}{"rev": float64(1), "views": float64(9)}}
+	calls := 0
+	doc, err := db.UpdateDocumentIfUnchanged(stale, func(d *gowrite.Document) error {
+		calls++
+		d.Data["views"] = d.Data["views"].(float64) + 1
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateDocumentIfUnchanged: %v", err)
+	}
+	if calls != 2 || doc.Data["views"] != float64(11) || patches[1]["rev"] != float64(3) {
+		t.Fatalf("calls=%d doc=%v patches=%v", calls, doc.Data, patches)
+	}
+	if stale.Data["views"] != float64(9) {
+		t.Fatal("mutate must not modify the caller's document")
+	}
+
+	_, err = db.UpdateDocumentIfUnchanged(stale, func(d *gowrite.Document) error {
+		mu.Lock()
+		version += 10 // a concurrent writer wins every round
+		mu.Unlock()
+		return nil
+	})
+	var ce *gowrite.ConflictError
+	if !errors.As(err, &ce) || !gowrite.IsConflict(err) || ce.Attempts != 5 {
+		t.Fatalf("err = %v, want a ConflictError after 5 attempts", err)
+	}
+
+	mu.Lock()
+	patches = nil
+	fresh := &gowrite.Document{ID: "d1", Database: "db", Collection: "c", Data: map[string]interface{}{"rev": float64(version)}}
+	mu.Unlock()
+	if _, err := db.UpdateDocumentIfUnchanged(fresh, func(d *gowrite.Document) error {
+		d.Data = nil
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateDocumentIfUnchanged with nil data: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(patches) != 1 || patches[0]["rev"] != float64(version) {
+		t.Fatalf("patches = %v", patches)
+	}
+}
+
+func TestUpdateDocumentIfUnchangedNullVersion(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.0"}`)
+		case r.Method == "GET":
+			// The document predates the version attribute.
+			fmt.Fprint(w, `{"$id":"d1","$databaseId":"db","$collectionId":"c","rev":null,"views":10}`)
+		case r.Method == "PATCH":
+			var body struct {
+				Queries []string `json:"queries"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			queries = body.Queries
+			if !slices.Contains(body.Queries, query.IsNull("rev")) {
+				fmt.Fprint(w, `{"total":0,"documents":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"total":1,"documents":[{"$id":"d1","rev":1,"views":11}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")).WithVersionAttribute("rev")
+	doc, err := db.UpdateDocumentIfUnchanged(&gowrite.Document{ID: "d1", Database: "db", Collection: "c"}, func(d *gowrite.Document) error {
+		d.Data["views"] = float64(11)
+		return nil
+	})
+	if err != nil || doc.Data["rev"] != float64(1) {
+		t.Fatalf("UpdateDocumentIfUnchanged = %v, %v (queries %v)", doc, err, queries)
+	}
+}
+
+func TestUpdateDocumentIfUnchangedSendsChanges(t *testing.T) {
+	const updatedAt = "2025-03-01T10:00:00.123456+00:00"
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Data    map[string]interface{} `json:"data"`
+			Queries []string               `json:"queries"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		data, _ := json.Marshal(body.Data)
+		mu.Lock()
+		requests = append(requests, fmt.Sprintf("%s %s %s %q", r.Method, r.URL.Path, data, body.Queries))
+		mu.Unlock()
+
+		doc := `{"$id":"d1","$updatedAt":"` + updatedAt + `","title":"old","author":{"$id":"u1","name":"Ann"}}`
+		switch r.URL.Path {
+		case "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.0"}`)
+		case "/v1/databases/db/collections/plain":
+			fmt.Fprint(w, `{"$id":"plain","attributes":[{"key":"title","type":"string","size":10,"status":"available"}]}`)
+		case "/v1/databases/db/collections/posts":
+			fmt.Fprint(w, `{"$id":"posts","attributes":[{"key":"author","type":"relationship","relatedCollection":"users","status":"available"}]}`)
+		case "/v1/databases/db/collections/plain/documents":
+			fmt.Fprintf(w, `{"total":1,"documents":[%s]}`, doc)
+		default:
+			fmt.Fprint(w, doc)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	ts, _ := time.Parse(time.RFC3339Nano, updatedAt)
+	rename := func(d *gowrite.Document) error {
+		d.Data["title"] = "new"
+		return nil
+	}
+	for _, collection := range []string{"plain", "posts"} {
+		doc := &gowrite.Document{ID: "d1", Database: "db", Collection: collection, UpdatedAt: ts,
+			Data: map[string]interface{}{"title": "old", "author": map[string]interface{}{"$id": "u1", "name": "Ann"}}}
+		if _, err := db.UpdateDocumentIfUnchanged(doc, rename); err != nil {
+			t.Fatalf("UpdateDocumentIfUnchanged(%s): %v", collection, err)
+		}
+	}
+
+	want := []string{
+		`GET /v1/health/version null []`,
+		`GET /v1/databases/db/collections/plain null []`,
+		fmt.Sprintf(`PATCH /v1/databases/db/collections/plain/documents {"title":"new"} %q`,
+			[]string{query.Equal("$id", "d1"), query.Equal("$updatedAt", updatedAt)}),
+		// Bulk updates reject collections with relationships.
+		`GET /v1/databases/db/collections/posts null []`,
+		`GET /v1/databases/db/collections/posts/documents/d1 null []`,
+		`PATCH /v1/databases/db/collections/posts/documents/d1 {"title":"new"} []`,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("requests:\n%s\nwant:\n%s", strings.Join(requests, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUpdateDocumentIfUnchangedNestedEdits(t *testing.T) {
+	var sent map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.0"}`)
+		case "/v1/databases/db/collections/posts":
+			fmt.Fprint(w, `{"$id":"posts","attributes":[]}`)
+		default:
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			sent = body.Data
+			fmt.Fprint(w, `{"total":1,"documents":[{"$id":"d1","$updatedAt":"2025-03-01T10:00:00.000+00:00"}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	doc := &gowrite.Document{ID: "d1", Database: "db", Collection: "posts", UpdatedAt: time.Now(),
+		Data: map[string]interface{}{
+			"tags":  []interface{}{"a", "b"},
+			"meta":  map[string]interface{}{"lang": "en"},
+			"title": "same",
+		}}
+	_, err := db.UpdateDocumentIfUnchanged(doc, func(d *gowrite.Document) error {
+		d.Data["tags"].([]interface{})[0] = "x"
+		d.Data["meta"].(map[string]interface{})["lang"] = "de"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateDocumentIfUnchanged: %v", err)
+	}
+	if got := fmt.Sprint(sent); got != "map[meta:map[lang:de] tags:[x b]]" {
+		t.Fatalf("sent %s", got)
+	}
+	if doc.Data["tags"].([]interface{})[0] != "a" {
+		t.Fatal("mutate changed the original document")
+	}
+}
+
+func TestCreateIndex(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"key":"by_title","type":"key","status":"processing","attributes":["title"],"lengths":[32]}`)
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	index, err := db.CreateIndex("db", "c", "by_title", gowrite.IndexKey, []string{"title"}, nil, []int{32})
+	if err != nil || index.Lengths[0] != 32 {
+		t.Fatalf("CreateIndex = %+v, %v", index, err)
+	}
+	if fmt.Sprint(got["lengths"]) != "[32]" || got["orders"] != nil {
+		t.Fatalf("payload = %v", got)
+	}
+}
+
+func TestWaitForIndex(t *testing.T) {
+	var polls, dropPolls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := "processing"
+		switch {
+		case r.URL.Path == "/v1/databases/db/collections/c/indexes/broken":
+			fmt.Fprint(w, `{"key":"broken","type":"unique","status":"failed","error":"duplicate values"}`)
+			return
+		case r.URL.Path == "/v1/databases/db/collections/c/indexes/dropped":
+			if dropPolls.Add(1) == 1 {
+				fmt.Fprint(w, `{"key":"dropped","type":"key","status":"deleting"}`)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Index not found","code":404,"type":"index_not_found"}`)
+			return
+		case polls.Add(1) == 3:
+			status = "available"
+		}
+		fmt.Fprintf(w, `{"key":"by_title","type":"key","status":%q,"attributes":["title"],"orders":["ASC"],"$createdAt":"2024-05-01T10:00:00.000+00:00"}`, status)
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	index, err := db.WaitForIndex(ctx, "db", "c", "by_title")
+	if err != nil || index.Status != gowrite.StatusAvailable || polls.Load() != 3 {
+		t.Fatalf("WaitForIndex = %+v, %v after %d polls", index, err, polls.Load())
+	}
+	if index.Type != gowrite.IndexKey || index.Attributes[0] != "title" || index.CreatedAt.IsZero() {
+		t.Fatalf("index = %+v", index)
+	}
+
+	_, err = db.WaitForIndex(ctx, "db", "c", "broken")
+	var pe *gowrite.ProvisioningError
+	if !errors.As(err, &pe) || pe.Status != gowrite.StatusFailed || pe.Message != "duplicate values" {
+		t.Fatalf("err = %v, want a ProvisioningError", err)
+	}
+
+	// An index being deleted is waited for until it is gone.
+	if _, err = db.WaitForIndex(ctx, "db", "c", "dropped"); !gowrite.IsNotFound(err) || dropPolls.Load() != 2 {
+		t.Fatalf("err = %v after %d polls, want not found", err, dropPolls.Load())
+	}
+}
+
+func TestWaitForCollectionReady(t *testing.T) {
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/databases/db/collections/c":
+			status := "processing"
+			if polls.Add(1) >= 2 {
+				status = "available"
+			}
+			fmt.Fprintf(w, `{"$id":"c","attributes":[{"key":"title","type":"string","size":10,"status":"available"}],`+
+				`"indexes":[{"key":"by_title","type":"key","status":%q}]}`, status)
+		case "/v1/databases/db/collections/broken":
+			fmt.Fprint(w, `{"$id":"broken","attributes":[{"key":"n","type":"integer","status":"failed","error":"out of memory"}]}`)
+		case "/v1/databases/db/collections/dropped":
+			fmt.Fprint(w, `{"$id":"dropped","attributes":[{"key":"n","type":"integer","status":"deleting"},`+
+				`{"key":"title","type":"string","size":10,"status":"available"}],"indexes":[{"key":"by_n","type":"key","status":"deleting"}]}`)
+		case "/v1/databases/db/collections/slow/attributes/n":
+			fmt.Fprint(w, `{"key":"n","type":"integer","status":"processing"}`)
+		}
+	}))
+	defer srv.Close()
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+
+	col, err := db.WaitForCollectionReady(context.Background(), "db", "c")
+	if err != nil || polls.Load() != 2 {
+		t.Fatalf("WaitForCollectionReady = %+v, %v", col, err)
+	}
+	if indexes, err := col.TypedIndexes(); err != nil || indexes[0].Status != gowrite.StatusAvailable {
+		t.Fatalf("TypedIndexes = %+v, %v", indexes, err)
+	}
+
+	_, err = db.WaitForCollectionReady(context.Background(), "db", "broken")
+	var pe *gowrite.ProvisioningError
+	if !errors.As(err, &pe) || pe.Kind != "attribute" || pe.Key != "n" || pe.Message != "out of memory" {
+		t.Fatalf("err = %v, want a ProvisioningError for n", err)
+	}
+
+	// Attributes and indexes being deleted do not keep the collection from being ready.
+	if _, err := db.WaitForCollectionReady(context.Background(), "db", "dropped"); err != nil {
+		t.Fatalf("WaitForCollectionReady with deleting items: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if _, err := db.WaitForAttribute(ctx, "db", "slow", "n"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context deadline", err)
+	}
+}
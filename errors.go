@@ -0,0 +1,104 @@
+package gowrite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error types reported by Appwrite in the "type" field of an error response.
+const (
+	ErrorTypeGeneralRouteNotFound   = "general_route_not_found"
+	ErrorTypeGeneralRateLimit       = "general_rate_limit_exceeded"
+	ErrorTypeDatabaseNotFound       = "database_not_found"
+	ErrorTypeCollectionNotFound     = "collection_not_found"
+	ErrorTypeDocumentNotFound       = "document_not_found"
+	ErrorTypeDocumentAlreadyExists  = "document_already_exists"
+	ErrorTypeAttributeNotFound      = "attribute_not_found"
+	ErrorTypeAttributeAlreadyExists = "attribute_already_exists"
+	ErrorTypeStorageFileNotFound    = "storage_file_not_found"
+	ErrorTypeUserNotFound           = "user_not_found"
+	ErrorTypeUserAlreadyExists      = "user_already_exists"
+)
+
+// AppwriteError is returned for every response with an error status. Code, Type,
+// Message and Version are decoded from the JSON error body when the server sends one.
+type AppwriteError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Type       string `json:"type"`
+	Message    string `json:"message"`
+	Version    string `json:"version"`
+	Body       []byte `json:"-"`
+}
+
+func newAppwriteError(statusCode int, body []byte) *AppwriteError {
+	e := &AppwriteError{StatusCode: statusCode, Body: body}
+	if err := json.Unmarshal(body, e); err != nil || e.Code == 0 {
+		e.Code = statusCode
+	}
+	return e
+}
+
+func (e *AppwriteError) Error() string {
+	if e.Type == "" && e.Message == "" {
+		return fmt.Sprintf("HTTP %d: %s", e.StatusCode, string(e.Body))
+	}
+	return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// AsAppwriteError unwraps err into an *AppwriteError if it contains one.
+func AsAppwriteError(err error) (*AppwriteError, bool) {
+	var e *AppwriteError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// HasErrorType reports whether err is an Appwrite error of the given type.
+func HasErrorType(err error, errorType string) bool {
+	e, ok := AsAppwriteError(err)
+	return ok && e.Type == errorType
+}
+
+func hasStatus(err error, status int) bool {
+	e, ok := AsAppwriteError(err)
+	return ok && e.StatusCode == status
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is a 409 response, e.g. an already existing document,
+// or a *ConflictError.
+func IsConflict(err error) bool {
+	var ce *ConflictError
+	return hasStatus(err, http.StatusConflict) || errors.As(err, &ce)
+}
+
+// IsRateLimited reports whether err is a 429 response.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsUnauthorized reports whether err is a 401 response.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// ConflictError is returned by UpdateDocumentIfUnchanged when the document kept being
+// modified concurrently until the attempts ran out.
+type ConflictError struct {
+	DatabaseID   string
+	CollectionID string
+	DocumentID   string
+	Attempts     int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("document %s/%s/%s changed concurrently, gave up after %d attempts", e.DatabaseID, e.CollectionID, e.DocumentID, e.Attempts)
+}
@@ -0,0 +1,577 @@
+package gowrite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DataFormat is the file format of collection exports and imports.
+type DataFormat string
+
+const (
+	// FormatNDJSON writes one JSON object per line with the attributes as typed values.
+	FormatNDJSON DataFormat = "ndjson"
+	// FormatCSV writes a header row followed by one row per document. Arrays and
+	// permissions are stored as JSON, and null is written as \N. Strings starting
+	// with a backslash get a second one, so empty and \N strings survive the trip.
+	FormatCSV DataFormat = "csv"
+)
+
+// csvNull is the cell FormatCSV writes for null.
+const csvNull = `\N`
+
+// importBatchSize is the number of rows written between two checkpoints.
+const importBatchSize = 100
+
+// ImportOptions controls ImportCollection.
+type ImportOptions struct {
+	Format DataFormat
+	// Concurrency is the number of documents written in parallel; 0 means 5.
+	Concurrency int
+	// Checkpoint is the path of a file recording how many rows were processed and
+	// which of them failed. An import given the same file skips the rows imported
+	// before and retries the failed ones, so an interrupted import resumes where it
+	// stopped and a rerun picks up rows whose cause was fixed. The file is removed
+	// once every row is imported; after a run with failures it lists only those rows.
+	Checkpoint string
+}
+
+// ImportSummary reports the outcome of ImportCollection.
+type ImportSummary struct {
+	// Rows is the number of rows read, including skipped and failed ones.
+	Rows int
+	// Skipped is the number of rows imported by an earlier run and skipped because
+	// of the checkpoint.
+	Skipped  int
+	Imported int
+	// Failed lists the rows that could not be parsed or written; Index is the
+	// zero-based row number. Rows that failed before the checkpoint are retried and
+	// only listed when they fail again.
+	Failed []*BulkError
+}
+
+// Err returns the row errors joined, or nil when every row was imported.
+func (s *ImportSummary) Err() error {
+	errs := make([]error, len(s.Failed))
+	for i, e := range s.Failed {
+		errs[i] = e
+	}
+	return errors.Join(errs...)
+}
+
+// ExportCollection streams the documents of a collection matching queries to w and
+// returns how many were written. Every row carries the document ID and permissions;
+// relationships are written as the IDs of the related documents.
+func (db *DatabaseService) ExportCollection(databaseID, collectionID string, w io.Writer, format DataFormat, queries []string) (int, error) {
+	return db.ExportCollectionCtx(context.Background(), databaseID, collectionID, w, format, queries)
+}
+
+// ExportCollectionCtx is like ExportCollection but uses ctx for the requests.
+func (db *DatabaseService) ExportCollectionCtx(ctx context.Context, databaseID, collectionID string, w io.Writer, format DataFormat, queries []string) (n int, err error) {
+	op := newOperation("databases", "exportCollection", "databaseId", databaseID, "collectionId", collectionID, "format", string(format))
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	col, err := db.GetCollectionCtx(ctx, databaseID, collectionID)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := col.TypedAttributes()
+	if err != nil {
+		return 0, err
+	}
+	codec := newRowCodec(attrs)
+
+	var write func(row map[string]interface{}) error
+	switch format {
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		write = func(row map[string]interface{}) error { return enc.Encode(row) }
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		defer func() {
+			cw.Flush()
+			if err == nil {
+				err = cw.Error()
+			}
+		}()
+		columns := append([]string{"$id", "$permissions"}, codec.keys...)
+		if err := cw.Write(columns); err != nil {
+			return 0, err
+		}
+		record := make([]string, len(columns))
+		write = func(row map[string]interface{}) error {
+			for i, key := range columns {
+				cell, err := codec.cell(row[key])
+				if err != nil {
+					return err
+				}
+				record[i] = cell
+			}
+			return cw.Write(record)
+		}
+	default:
+		return 0, fmt.Errorf("unknown data format %q", format)
+	}
+
+	for doc, err := range db.IterateDocuments(ctx, databaseID, collectionID, queries) {
+		if err != nil {
+			return n, err
+		}
+		if err := write(codec.row(doc)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ImportCollection reads documents written by ExportCollection, or in the same
+// format, from r and upserts them into a collection with bounded concurrency. Rows
+// without an ID are created with a unique one, so they are duplicated when an
+// interrupted import repeats them.
+//
+// Rows that cannot be parsed or written are reported in the summary and do not stop
+// the import; the returned error is only set when reading, the checkpoint or ctx fail.
+func (db *DatabaseService) ImportCollection(databaseID, collectionID string, r io.Reader, opts ImportOptions) (*ImportSummary, error) {
+	return db.ImportCollectionCtx(context.Background(), databaseID, collectionID, r, opts)
+}
+
+// ImportCollectionCtx is like ImportCollection but uses ctx for the requests.
+func (db *DatabaseService) ImportCollectionCtx(ctx context.Context, databaseID, collectionID string, r io.Reader, opts ImportOptions) (summary *ImportSummary, err error) {
+	op := newOperation("databases", "importCollection", "databaseId", databaseID, "collectionId", collectionID, "format", string(opts.Format))
+	ctx, end := db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	summary = &ImportSummary{}
+	var rows rowReader
+	switch opts.Format {
+	case FormatNDJSON:
+		rows = &ndjsonReader{r: bufio.NewReader(r)}
+	case FormatCSV:
+		col, err := db.GetCollectionCtx(ctx, databaseID, collectionID)
+		if err != nil {
+			return summary, err
+		}
+		attrs, err := col.TypedAttributes()
+		if err != nil {
+			return summary, err
+		}
+		if rows, err = newCSVReader(r, newRowCodec(attrs)); err != nil {
+			return summary, err
+		}
+	default:
+		return summary, fmt.Errorf("unknown data format %q", opts.Format)
+	}
+
+	resume, err := readCheckpoint(opts.Checkpoint)
+	if err != nil {
+		return summary, err
+	}
+	failedBefore := make(map[int]bool, len(resume.Failed))
+	for _, f := range resume.Failed {
+		failedBefore[f.Index] = true
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = bulkConcurrency
+	}
+
+	type pendingRow struct {
+		index int
+		doc   *Document
+	}
+	batch := make([]pendingRow, 0, importBatchSize)
+	flush := func() error {
+		errs := runConcurrent(len(batch), concurrency, func(i int) error {
+			doc := batch[i].doc
+			var err error
+			if doc.ID == "" {
+				_, err = db.upsertDocumentFallback(ctx, databaseID, collectionID, "", doc.Data, doc.Permissions)
+			} else {
+				_, err = db.UpsertDocumentCtx(ctx, databaseID, collectionID, doc.ID, doc.Data, doc.Permissions)
+			}
+			return err
+		})
+		if ctx.Err() != nil {
+			// The batch may be partly written; leave the checkpoint before it.
+			return ctx.Err()
+		}
+		for i, err := range errs {
+			if err != nil {
+				summary.Failed = append(summary.Failed, &BulkError{Index: batch[i].index, DocumentID: batch[i].doc.ID, Err: err})
+			} else {
+				summary.Imported++
+			}
+		}
+		batch = batch[:0]
+		// Failed rows of the earlier run that were not read again yet stay recorded.
+		failed := summary.Failed
+		for _, f := range resume.Failed {
+			if f.Index >= summary.Rows {
+				failed = append(failed, &BulkError{Index: f.Index, DocumentID: f.DocumentID, Err: errors.New(f.Error)})
+			}
+		}
+		return writeCheckpoint(opts.Checkpoint, max(summary.Rows, resume.Rows), failed)
+	}
+
+	for {
+		doc, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return summary, err
+		}
+		index := summary.Rows
+		summary.Rows++
+		switch {
+		case index < resume.Rows && !failedBefore[index]:
+			summary.Skipped++
+			continue
+		case rowErr != nil:
+			summary.Failed = append(summary.Failed, &BulkError{Index: index, Err: rowErr.err})
+			continue
+		}
+
+		batch = append(batch, pendingRow{index, doc})
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return summary, err
+	}
+	// Parse errors are recorded before the write errors of the same batch.
+	slices.SortFunc(summary.Failed, func(a, b *BulkError) int { return a.Index - b.Index })
+	if len(summary.Failed) > 0 {
+		// Keep the failed rows, so a rerun retries them and skips everything else.
+		return summary, writeCheckpoint(opts.Checkpoint, summary.Rows, summary.Failed)
+	}
+	if opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// checkpoint is the content of an import checkpoint file.
+type checkpoint struct {
+	Rows   int         `json:"rows"`
+	Failed []failedRow `json:"failed,omitempty"`
+}
+
+// failedRow records a row that failed before the checkpoint and is retried on resume.
+type failedRow struct {
+	Index      int    `json:"index"`
+	DocumentID string `json:"documentId,omitempty"`
+	Error      string `json:"error"`
+}
+
+func readCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{}
+	if path == "" {
+		return cp, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// writeCheckpoint replaces the checkpoint file through a rename, so an interruption
+// never leaves it half written.
+func writeCheckpoint(path string, rows int, failed []*BulkError) error {
+	if path == "" {
+		return nil
+	}
+	cp := checkpoint{Rows: rows}
+	for _, f := range failed {
+		cp.Failed = append(cp.Failed, failedRow{Index: f.Index, DocumentID: f.DocumentID, Error: f.Err.Error()})
+	}
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// rowCodec converts documents to and from export rows using the collection's
+// attributes.
+type rowCodec struct {
+	attrs map[string]TypedAttribute
+	// keys are the attribute keys in collection order.
+	keys []string
+}
+
+func newRowCodec(attrs AttributeList) *rowCodec {
+	c := &rowCodec{attrs: make(map[string]TypedAttribute, len(attrs))}
+	for _, a := range attrs {
+		key := a.Base().Key
+		c.attrs[key] = a
+		c.keys = append(c.keys, key)
+	}
+	return c
+}
+
+// row flattens a document into its ID, permissions and attributes, replacing related
+// documents with their IDs.
+func (c *rowCodec) row(doc *Document) map[string]interface{} {
+	row := make(map[string]interface{}, len(doc.Data)+2)
+	for k, v := range doc.Data {
+		if _, ok := c.attrs[k].(*RelationshipAttribute); ok {
+			v = relatedIDs(v)
+		}
+		row[k] = v
+	}
+	row["$id"] = doc.ID
+	permissions := doc.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	row["$permissions"] = permissions
+	return row
+}
+
+// relatedIDs replaces related documents in v with their IDs.
+func relatedIDs(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v["$id"]
+	case []interface{}:
+		ids := make([]interface{}, len(v))
+		for i, item := range v {
+			ids[i] = relatedIDs(item)
+		}
+		return ids
+	}
+	return v
+}
+
+// cell formats a value for CSV: strings as is with a leading backslash doubled, null
+// as csvNull, and any other value as JSON.
+func (c *rowCodec) cell(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return csvNull, nil
+	case string:
+		if strings.HasPrefix(v, `\`) {
+			return `\` + v, nil
+		}
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// parseCell converts a CSV cell back into the value of the attribute key.
+func (c *rowCodec) parseCell(key, s string) (interface{}, error) {
+	attr, ok := c.attrs[key]
+	if !ok {
+		return strings.TrimPrefix(s, `\`), nil
+	}
+	if attr.Base().Array || isToMany(attr) {
+		var list []interface{}
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		if err := dec.Decode(&list); err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", key, err)
+		}
+		return list, nil
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch attr.(type) {
+	case *IntegerAttribute:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case *FloatAttribute:
+		v, err = strconv.ParseFloat(s, 64)
+	case *BooleanAttribute:
+		v, err = strconv.ParseBool(s)
+	default:
+		v = strings.TrimPrefix(s, `\`)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("attribute %q: %w", key, err)
+	}
+	return v, nil
+}
+
+// isToMany reports whether a relationship attribute holds several documents.
+func isToMany(attr TypedAttribute) bool {
+	r, ok := attr.(*RelationshipAttribute)
+	if !ok {
+		return false
+	}
+	switch r.Type {
+	case RelationManyToMany:
+		return true
+	case RelationOneToMany:
+		return r.Side != "child"
+	case RelationManyToOne:
+		return r.Side == "child"
+	}
+	return false
+}
+
+// rowReader reads the documents of an import one row at a time. It returns io.EOF at
+// the end and a *rowError for a malformed row, after which reading continues.
+type rowReader interface {
+	next() (*Document, error)
+}
+
+type rowError struct {
+	err error
+}
+
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+type ndjsonReader struct {
+	r *bufio.Reader
+}
+
+func (n *ndjsonReader) next() (*Document, error) {
+	for {
+		line, err := n.r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		var row map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&row); err != nil {
+			return nil, &rowError{err}
+		}
+		doc, err := documentFromRow(row)
+		if err != nil {
+			return nil, &rowError{err}
+		}
+		return doc, nil
+	}
+}
+
+// documentFromRow takes the ID and permissions out of row; the remaining non-system
+// fields become the document data.
+func documentFromRow(row map[string]interface{}) (*Document, error) {
+	doc := &Document{Data: make(map[string]interface{}, len(row))}
+	for k, v := range row {
+		switch k {
+		case "$id":
+			id, ok := v.(string)
+			if !ok && v != nil {
+				return nil, fmt.Errorf("$id is %T, not a string", v)
+			}
+			doc.ID = id
+		case "$permissions":
+			list, ok := v.([]interface{})
+			if !ok && v != nil {
+				return nil, fmt.Errorf("$permissions is %T, not a list", v)
+			}
+			for _, p := range list {
+				s, ok := p.(string)
+				if !ok {
+					return nil, fmt.Errorf("permission %v is not a string", p)
+				}
+				doc.Permissions = append(doc.Permissions, s)
+			}
+		default:
+			if !strings.HasPrefix(k, "$") {
+				doc.Data[k] = v
+			}
+		}
+	}
+	return doc, nil
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+	codec  *rowCodec
+}
+
+func newCSVReader(r io.Reader, codec *rowCodec) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv input has no header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &csvReader{r: cr, header: header, codec: codec}, nil
+}
+
+func (c *csvReader) next() (*Document, error) {
+	record, err := c.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &rowError{err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Data: make(map[string]interface{}, len(record))}
+	for i, key := range c.header {
+		s := record[i]
+		switch {
+		case key == "$id":
+			doc.ID = s
+		case key == "$permissions":
+			if s != "" {
+				if err := json.Unmarshal([]byte(s), &doc.Permissions); err != nil {
+					return nil, &rowError{fmt.Errorf("$permissions: %w", err)}
+				}
+			}
+		case strings.HasPrefix(key, "$") || s == csvNull:
+			// Other system columns are read only, and null is left out.
+		default:
+			v, err := c.codec.parseCell(key, s)
+			if err != nil {
+				return nil, &rowError{err}
+			}
+			doc.Data[key] = v
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,256 @@
+package gowrite_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+)
+
+// exportServer serves a posts collection with two documents and records the data of
+// upserted documents. Upserts of the document "bad" are rejected.
+func exportServer(t *testing.T) (*gowrite.DatabaseService, map[string]string) {
+	var mu sync.Mutex
+	written := make(map[string]string)
+	const base = "/v1/databases/db/collections/posts"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.4"}`)
+		case r.URL.Path == base:
+			fmt.Fprint(w, `{"$id":"posts","attributes":[`+
+				`{"key":"title","type":"string","status":"available"},`+
+				`{"key":"views","type":"integer","status":"available"},`+
+				`{"key":"tags","type":"string","array":true,"status":"available"},`+
+				`{"key":"author","type":"relationship","relationType":"manyToOne","side":"parent","status":"available"}]}`)
+		case r.URL.Path == base+"/documents":
+			fmt.Fprint(w, `{"total":3,"documents":[`+
+				`{"$id":"p1","$permissions":["read(\"any\")"],"title":"Hello, world","views":3,"tags":["a","b"],"author":{"$id":"u1","name":"Ann"}},`+
+				`{"$id":"p2","$permissions":[],"title":"\\N","views":null,"tags":[],"author":null},`+
+				`{"$id":"p3","$permissions":[],"title":"","views":0,"tags":null,"author":null}]}`)
+		case r.Method == "PUT":
+			id := strings.TrimPrefix(r.URL.Path, base+"/documents/")
+			if id == "bad" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"Invalid document structure","code":400,"type":"document_invalid_structure"}`)
+				return
+			}
+			var body struct {
+				Data        map[string]interface{} `json:"data"`
+				Permissions []string               `json:"permissions"`
+			}
+			dec := json.NewDecoder(r.Body)
+			dec.UseNumber()
+			dec.Decode(&body)
+			b, _ := json.Marshal(body)
+			mu.Lock()
+			written[id] = string(b)
+			mu.Unlock()
+			fmt.Fprintf(w, `{"$id":%q}`, id)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")), written
+}
+
+func TestExportImportCSV(t *testing.T) {
+	db, written := exportServer(t)
+
+	var buf bytes.Buffer
+	n, err := db.ExportCollection("db", "posts", &buf, gowrite.FormatCSV, nil)
+	if err != nil || n != 3 {
+		t.Fatalf("ExportCollection = %d, %v", n, err)
+	}
+	want := "$id,$permissions,title,views,tags,author\n" +
+		`p1,"[""read(\""any\"")""]","Hello, world",3,"[""a"",""b""]",u1` + "\n" +
+		`p2,[],\\N,\N,[],\N` + "\n" +
+		`p3,[],,0,\N,\N` + "\n"
+	if buf.String() != want {
+		t.Fatalf("csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	summary, err := db.ImportCollection("db", "posts", &buf, gowrite.ImportOptions{Format: gowrite.FormatCSV})
+	if err != nil || summary.Imported != 3 || summary.Err() != nil {
+		t.Fatalf("ImportCollection = %+v, %v", summary, err)
+	}
+	if got := written["p1"]; got != `{"data":{"author":"u1","tags":["a","b"],"title":"Hello, world","views":3},"permissions":["read(\"any\")"]}` {
+		t.Fatalf("p1 written as %s", got)
+	}
+	if got := written["p2"]; got != `{"data":{"tags":[],"title":"\\N"},"permissions":[]}` {
+		t.Fatalf("p2 written as %s", got)
+	}
+	if got := written["p3"]; got != `{"data":{"title":"","views":0},"permissions":[]}` {
+		t.Fatalf("p3 written as %s", got)
+	}
+}
+
+func TestImportNDJSONResume(t *testing.T) {
+	db, written := exportServer(t)
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	if err := os.WriteFile(checkpoint, []byte(`{"rows":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"$id":"done","title":"imported before the interruption"}` + "\n" +
+		`{"$id":"p3","$permissions":["read(\"any\")"],"title":"Third","views":9007199254740993}` + "\n" +
+		"\n" +
+		`{"$id":"broken",` + "\n" +
+		`{"$id":"bad","title":"rejected"}` + "\n"
+	summary, err := db.ImportCollection("db", "posts", strings.NewReader(input), gowrite.ImportOptions{
+		Format:     gowrite.FormatNDJSON,
+		Checkpoint: checkpoint,
+	})
+	if err != nil {
+		t.Fatalf("ImportCollection: %v", err)
+	}
+	if summary.Rows != 4 || summary.Skipped != 1 || summary.Imported != 1 || len(summary.Failed) != 2 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if summary.Failed[0].Index != 2 || summary.Failed[1].Index != 3 || summary.Failed[1].DocumentID != "bad" {
+		t.Fatalf("failed rows = %v", summary.Err())
+	}
+	if _, ok := written["done"]; ok {
+		t.Fatal("row before the checkpoint was imported again")
+	}
+	if !strings.Contains(written["p3"], `"views":9007199254740993`) {
+		t.Fatalf("p3 written as %s", written["p3"])
+	}
+	// The checkpoint keeps only the failed rows for a rerun.
+	b, err := os.ReadFile(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp struct {
+		Rows   int `json:"rows"`
+		Failed []struct {
+			Index int `json:"index"`
+		} `json:"failed"`
+	}
+	if err := json.Unmarshal(b, &cp); err != nil || cp.Rows != 4 || len(cp.Failed) != 2 || cp.Failed[0].Index != 2 || cp.Failed[1].Index != 3 {
+		t.Fatalf("checkpoint = %s", b)
+	}
+}
+
+func TestImportResumeKeepsFailures(t *testing.T) {
+	db, written := exportServer(t)
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+
+	// The first batch holds a rejected row; the run is interrupted in the second.
+	var input strings.Builder
+	input.WriteString(`{"$id":"bad","title":"rejected"}` + "\n")
+	for i := 1; i < 100; i++ {
+		fmt.Fprintf(&input, `{"$id":"r%d","title":"row"}`+"\n", i)
+	}
+	input.WriteString(`{"$id":"stop","title":"interrupted"}` + "\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	db.Client.Use(func(next gowrite.Handler) gowrite.Handler {
+		return func(req *gowrite.Request) (*gowrite.Response, error) {
+			if strings.HasSuffix(req.HTTP.URL.Path, "/documents/stop") {
+				cancel()
+			}
+			return next(req)
+		}
+	})
+	opts := gowrite.ImportOptions{Format: gowrite.FormatNDJSON, Checkpoint: checkpoint}
+	if _, err := db.ImportCollectionCtx(ctx, "db", "posts", strings.NewReader(input.String()), opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted ImportCollection = %v", err)
+	}
+
+	delete(written, "r1")
+	summary, err := db.ImportCollection("db", "posts", strings.NewReader(input.String()), opts)
+	if err != nil {
+		t.Fatalf("resumed ImportCollection: %v", err)
+	}
+	if summary.Rows != 101 || summary.Skipped != 99 || summary.Imported != 1 || len(summary.Failed) != 1 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if f := summary.Failed[0]; f.Index != 0 || f.DocumentID != "bad" || !strings.Contains(f.Err.Error(), "Invalid document structure") {
+		t.Fatalf("failed rows = %v", summary.Err())
+	}
+	if _, ok := written["r1"]; ok {
+		t.Fatal("row before the checkpoint was imported again")
+	}
+}
+
+func TestImportResumeRetriesFailures(t *testing.T) {
+	db, written := exportServer(t)
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	// The earlier run failed on the first row, whose cause has been fixed since.
+	cp := `{"rows":2,"failed":[{"index":0,"documentId":"fixed","error":"Invalid document structure"}]}`
+	if err := os.WriteFile(checkpoint, []byte(cp), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"$id":"fixed","title":"retried"}` + "\n" +
+		`{"$id":"done","title":"imported before"}` + "\n" +
+		`{"$id":"new","title":"after the checkpoint"}` + "\n"
+	summary, err := db.ImportCollection("db", "posts", strings.NewReader(input), gowrite.ImportOptions{
+		Format:     gowrite.FormatNDJSON,
+		Checkpoint: checkpoint,
+	})
+	if err != nil || summary.Err() != nil {
+		t.Fatalf("ImportCollection = %+v, %v", summary, err)
+	}
+	if summary.Rows != 3 || summary.Skipped != 1 || summary.Imported != 2 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if _, ok := written["fixed"]; !ok {
+		t.Fatal("row that failed before the checkpoint was not retried")
+	}
+	if _, ok := written["done"]; ok {
+		t.Fatal("row before the checkpoint was imported again")
+	}
+}
+
+func TestImportRerunRetriesOnlyFailures(t *testing.T) {
+	db, _ := exportServer(t)
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	db.Client.Use(func(next gowrite.Handler) gowrite.Handler {
+		return func(req *gowrite.Request) (*gowrite.Response, error) {
+			if strings.Contains(req.HTTP.URL.Path, "/documents") {
+				mu.Lock()
+				sent = append(sent, req.HTTP.Method+" "+req.HTTP.URL.Path)
+				mu.Unlock()
+			}
+			return next(req)
+		}
+	})
+
+	input := `{"$id":"ok","title":"imported"}` + "\n" +
+		`{"$id":"bad","title":"rejected"}` + "\n" +
+		`{"title":"without an ID"}` + "\n"
+	opts := gowrite.ImportOptions{Format: gowrite.FormatNDJSON, Checkpoint: checkpoint}
+	summary, err := db.ImportCollection("db", "posts", strings.NewReader(input), opts)
+	if err != nil || summary.Imported != 2 || len(summary.Failed) != 1 {
+		t.Fatalf("ImportCollection = %+v, %v", summary, err)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatalf("checkpoint of a run with failures: %v", err)
+	}
+
+	sent = nil
+	summary, err = db.ImportCollection("db", "posts", strings.NewReader(input), opts)
+	if err != nil || summary.Skipped != 2 || summary.Imported != 0 || len(summary.Failed) != 1 {
+		t.Fatalf("rerun ImportCollection = %+v, %v", summary, err)
+	}
+	if want := "[PUT /v1/databases/db/collections/posts/documents/bad]"; fmt.Sprint(sent) != want {
+		t.Fatalf("rerun sent %v, want %s", sent, want)
+	}
+}
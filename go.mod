@@ -6,6 +6,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/json-iterator/go v1.1.12
 	github.com/redis/go-redis/v9 v9.16.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
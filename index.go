@@ -0,0 +1,170 @@
+package gowrite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// IndexType defines allowed index types when creating indexes.
+type IndexType string
+
+const (
+	IndexKey      IndexType = "key"
+	IndexUnique   IndexType = "unique"
+	IndexFulltext IndexType = "fulltext"
+)
+
+// Index represents an index of an Appwrite collection.
+type Index struct {
+	Key        string    `json:"key"`
+	Type       IndexType `json:"type"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error"`
+	Attributes []string  `json:"attributes"`
+	Orders     []string  `json:"orders"`
+	Lengths    []int     `json:"lengths"`
+	CreatedAt  time.Time `json:"$createdAt"`
+	UpdatedAt  time.Time `json:"$updatedAt"`
+}
+
+// UnmarshalJSON parses the Appwrite timestamps of an index.
+func (idx *Index) UnmarshalJSON(b []byte) error {
+	type Alias Index
+	aux := &struct {
+		*Alias
+		CreatedAt string `json:"$createdAt"`
+		UpdatedAt string `json:"$updatedAt"`
+	}{
+		Alias: (*Alias)(idx),
+	}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if idx.CreatedAt, err = parseTimestamp(aux.CreatedAt); err != nil {
+		return err
+	}
+	idx.UpdatedAt, err = parseTimestamp(aux.UpdatedAt)
+	return err
+}
+
+// TypedIndexes decodes the indexes of the collection into Index values.
+func (c *Collection) TypedIndexes() ([]*Index, error) {
+	b, err := json.Marshal(c.Indexes)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []*Index
+	if err := json.Unmarshal(b, &indexes); err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
+
+// CreateIndex creates an index on attributes of a collection. orders holds "ASC" or
+// "DESC" per attribute and lengths the indexed prefix length of string attributes;
+// both may be nil. The index is processed in the background; use WaitForIndex to wait
+// until it is available.
+func (db *DatabaseService) CreateIndex(databaseID, collectionID, key string, indexType IndexType, attributes, orders []string, lengths []int) (*Index, error) {
+	return db.CreateIndexCtx(context.Background(), databaseID, collectionID, key, indexType, attributes, orders, lengths)
+}
+
+// CreateIndexCtx is like CreateIndex but uses ctx for the request.
+func (db *DatabaseService) CreateIndexCtx(ctx context.Context, databaseID, collectionID, key string, indexType IndexType, attributes, orders []string, lengths []int) (*Index, error) {
+	payload := map[string]interface{}{
+		"key":        key,
+		"type":       indexType,
+		"attributes": attributes,
+	}
+	if orders != nil {
+		payload["orders"] = orders
+	}
+	if lengths != nil {
+		payload["lengths"] = lengths
+	}
+
+	path := fmt.Sprintf("/databases/%s/collections/%s/indexes", databaseID, collectionID)
+	op := newOperation("databases", "createIndex", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", path, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var index Index
+	if err = json.Unmarshal(respBody, &index); err != nil {
+		return nil, err
+	}
+
+	return &index, nil
+}
+
+// GetIndex retrieves an index of a collection.
+func (db *DatabaseService) GetIndex(databaseID, collectionID, key string) (*Index, error) {
+	return db.GetIndexCtx(context.Background(), databaseID, collectionID, key)
+}
+
+// GetIndexCtx is like GetIndex but uses ctx for the request.
+func (db *DatabaseService) GetIndexCtx(ctx context.Context, databaseID, collectionID, key string) (*Index, error) {
+	path := fmt.Sprintf("/databases/%s/collections/%s/indexes/%s", databaseID, collectionID, key)
+	op := newOperation("databases", "getIndex", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var index Index
+	if err = json.Unmarshal(respBody, &index); err != nil {
+		return nil, err
+	}
+
+	return &index, nil
+}
+
+// ListIndexes retrieves the indexes of a collection.
+func (db *DatabaseService) ListIndexes(databaseID, collectionID string, queries []string) ([]*Index, error) {
+	return db.ListIndexesCtx(context.Background(), databaseID, collectionID, queries)
+}
+
+// ListIndexesCtx is like ListIndexes but uses ctx for the request.
+func (db *DatabaseService) ListIndexesCtx(ctx context.Context, databaseID, collectionID string, queries []string) ([]*Index, error) {
+	q := url.Values{}
+	for _, qs := range queries {
+		q.Add("queries[]", qs)
+	}
+	path := fmt.Sprintf("/databases/%s/collections/%s/indexes", databaseID, collectionID)
+	if encoded := q.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+	op := newOperation("databases", "listIndexes", "databaseId", databaseID, "collectionId", collectionID)
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Indexes []*Index `json:"indexes"`
+	}
+	if err = json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Indexes, nil
+}
+
+// DeleteIndex deletes an index of a collection.
+func (db *DatabaseService) DeleteIndex(databaseID, collectionID, key string) error {
+	return db.DeleteIndexCtx(context.Background(), databaseID, collectionID, key)
+}
+
+// DeleteIndexCtx is like DeleteIndex but uses ctx for the request.
+func (db *DatabaseService) DeleteIndexCtx(ctx context.Context, databaseID, collectionID, key string) error {
+	path := fmt.Sprintf("/databases/%s/collections/%s/indexes/%s", databaseID, collectionID, key)
+	op := newOperation("databases", "deleteIndex", "databaseId", databaseID, "collectionId", collectionID, "key", key)
+	_, err := db.Client.sendRequest(ctx, op, "DELETE", path, nil)
+	return err
+}
@@ -0,0 +1,190 @@
+package gowrite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+
+	"github.com/dm-vev/gowrite/query"
+)
+
+// defaultPageSize is the number of documents requested per page when listing.
+const defaultPageSize = 800
+
+// documentList is the response of the list documents endpoint.
+type documentList struct {
+	Total     int         `json:"total"`
+	Documents []*Document `json:"documents"`
+}
+
+// listDocumentsPage fetches a single page of documents with the queries as given.
+func (db *DatabaseService) listDocumentsPage(ctx context.Context, op Operation, databaseID, collectionID string, queries []string) (*documentList, error) {
+	q := url.Values{}
+	for _, qs := range queries {
+		q.Add("queries[]", qs)
+	}
+	path := fmt.Sprintf("/databases/%s/collections/%s/documents", databaseID, collectionID)
+	if encoded := q.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+
+	respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result documentList
+	if err = _json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// pagingQueries holds the queries of a listing with limit, offset and cursors split off.
+type pagingQueries struct {
+	base         []string
+	limit        int // -1 when no limit was given
+	offset       int
+	cursorAfter  string
+	cursorBefore bool
+}
+
+func splitPagingQueries(queries []string) pagingQueries {
+	pq := pagingQueries{base: make([]string, 0, len(queries)), limit: -1}
+	for _, q := range queries {
+		opts, err := query.Parse(q)
+		if err != nil {
+			pq.base = append(pq.base, q)
+			continue
+		}
+		var first interface{}
+		if opts.Values != nil && len(*opts.Values) > 0 {
+			first = (*opts.Values)[0]
+		}
+		switch opts.Method {
+		case "limit":
+			if n, ok := first.(float64); ok {
+				pq.limit = int(n)
+			}
+		case "offset":
+			if n, ok := first.(float64); ok {
+				pq.offset = int(n)
+			}
+		case "cursorAfter":
+			pq.cursorAfter, _ = first.(string)
+		case "cursorBefore":
+			pq.cursorBefore = true
+		default:
+			pq.base = append(pq.base, q)
+		}
+	}
+	return pq
+}
+
+// IterateDocuments returns an iterator over the documents matching queries. Pages are
+// fetched lazily with cursor pagination, which stays stable under concurrent writes,
+// and stopping the loop stops fetching. A limit query caps the number of documents
+// yielded, an offset query skips documents before the first one and order queries
+// keep their effect. Results are not cached.
+//
+//	for doc, err := range db.IterateDocuments(ctx, dbID, colID, nil) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (db *DatabaseService) IterateDocuments(ctx context.Context, databaseID, collectionID string, queries []string) iter.Seq2[*Document, error] {
+	return func(yield func(*Document, error) bool) {
+		pq := splitPagingQueries(queries)
+		if pq.cursorBefore {
+			yield(nil, errors.New("gowrite: IterateDocuments does not support cursorBefore"))
+			return
+		}
+
+		remaining := pq.limit
+		cursor := pq.cursorAfter
+		offset := pq.offset
+		for remaining != 0 {
+			pageSize := db.ListOptions.pageSize()
+			if remaining > 0 && remaining < pageSize {
+				pageSize = remaining
+			}
+
+			q := append([]string(nil), pq.base...)
+			q = append(q, query.Limit(int64(pageSize)))
+			if offset > 0 {
+				q = append(q, query.Offset(offset))
+			}
+			if cursor != "" {
+				q = append(q, query.CursorAfter(cursor))
+			}
+
+			op := newOperation("databases", "listDocuments", "databaseId", databaseID, "collectionId", collectionID, "cursor", cursor)
+			page, err := db.listDocumentsPage(ctx, op, databaseID, collectionID, q)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, doc := range page.Documents {
+				if !yield(doc, nil) {
+					return
+				}
+			}
+			if len(page.Documents) < pageSize {
+				return
+			}
+			if remaining > 0 {
+				remaining -= len(page.Documents)
+			}
+			cursor = page.Documents[len(page.Documents)-1].ID
+			offset = 0
+		}
+	}
+}
+
+// collectionsPageSize is the number of collections requested per page.
+const collectionsPageSize = 100
+
+// IterateCollections yields every collection of a database, unlike ListCollections
+// which returns the server's first page only.
+func (db *DatabaseService) IterateCollections(ctx context.Context, databaseID string) iter.Seq2[*Collection, error] {
+	return func(yield func(*Collection, error) bool) {
+		cursor := ""
+		for {
+			q := url.Values{}
+			q.Add("queries[]", query.Limit(collectionsPageSize))
+			if cursor != "" {
+				q.Add("queries[]", query.CursorAfter(cursor))
+			}
+			path := fmt.Sprintf("/databases/%s/collections?%s", databaseID, q.Encode())
+			op := newOperation("databases", "listCollections", "databaseId", databaseID, "cursor", cursor)
+			respBody, err := db.Client.sendRequest(ctx, op, "GET", path, nil)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var result struct {
+				Collections []*Collection `json:"collections"`
+			}
+			if err := json.Unmarshal(respBody, &result); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, c := range result.Collections {
+				if !yield(c, nil) {
+					return
+				}
+			}
+			if len(result.Collections) < collectionsPageSize {
+				return
+			}
+			cursor = result.Collections[len(result.Collections)-1].ID
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gowrite
+
+import (
+	"net/http"
+)
+
+// Operation identifies the logical API call a request belongs to.
+type Operation struct {
+	// Service is the API group, e.g. "databases", "storage" or "users".
+	Service string
+	// Method is the API method within the service, e.g. "getDocument".
+	Method string
+	// Params holds the resource IDs of the call keyed by their API name,
+	// e.g. "databaseId", "collectionId", "documentId".
+	Params map[string]string
+}
+
+// Name returns the qualified operation name, e.g. "databases.getDocument".
+func (op Operation) Name() string {
+	return op.Service + "." + op.Method
+}
+
+// newOperation builds an Operation from alternating param names and values.
+func newOperation(service, method string, params ...string) Operation {
+	op := Operation{Service: service, Method: method}
+	if len(params) > 1 {
+		op.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			op.Params[params[i]] = params[i+1]
+		}
+	}
+	return op
+}
+
+// Request is passed through the middleware chain. HTTP is the prepared request;
+// middlewares may add headers to it before calling the next handler.
+type Request struct {
+	Operation Operation
+	HTTP      *http.Request
+}
+
+// Response is the outcome of a request once its body has been read. It is also
+// returned alongside an *AppwriteError for error statuses. Body is empty for downloads
+// streamed to a writer, such as StorageService.DownloadFileTo.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// BodySize is the number of body bytes received, including those streamed to a
+	// writer instead of being kept in Body.
+	BodySize int64
+}
+
+// Handler executes a request. The innermost handler applies the client's rate limiter
+// and retry policy, so a middleware sees each logical call exactly once.
+type Handler func(req *Request) (*Response, error)
+
+// Middleware wraps a Handler with additional behavior such as tracing or logging.
+type Middleware func(next Handler) Handler
+
+// Use appends middlewares to the client. The first middleware added is the outermost
+// one. Use must not be called concurrently with requests.
+func (client *AppwriteClient) Use(mw ...Middleware) *AppwriteClient {
+	client.middlewares = append(client.middlewares, mw...)
+	return client
+}
+
+func (client *AppwriteClient) handler() Handler {
+	h := Handler(client.roundTrip)
+	for i := len(client.middlewares) - 1; i >= 0; i-- {
+		h = client.middlewares[i](h)
+	}
+	return h
+}
@@ -0,0 +1,43 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/query"
+)
+
+// Backfill calls fn for every document matching queries and writes the attributes it
+// returns back to the document. Documents for which fn returns nil are left alone. It
+// returns the number of updated documents.
+func Backfill(ctx context.Context, db *gowrite.DatabaseService, databaseID, collectionID string, queries []string, fn func(doc *gowrite.Document) (map[string]interface{}, error)) (int, error) {
+	updated := 0
+	for doc, err := range db.IterateDocuments(ctx, databaseID, collectionID, queries) {
+		if err != nil {
+			return updated, err
+		}
+		data, err := fn(doc)
+		if err != nil {
+			return updated, fmt.Errorf("document %s: %w", doc.ID, err)
+		}
+		if data == nil {
+			continue
+		}
+		if _, err := db.UpdateDocumentCtx(ctx, databaseID, collectionID, doc.ID, data, nil); err != nil {
+			return updated, fmt.Errorf("document %s: %w", doc.ID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// CopyAttribute copies the value of attribute from to attribute to in every document
+// where from is set, which together with creating to and deleting from renames an
+// attribute. It returns the number of updated documents.
+func CopyAttribute(ctx context.Context, db *gowrite.DatabaseService, databaseID, collectionID, from, to string) (int, error) {
+	queries := []string{query.IsNotNull(from)}
+	return Backfill(ctx, db, databaseID, collectionID, queries, func(doc *gowrite.Document) (map[string]interface{}, error) {
+		return map[string]interface{}{to: doc.Data[from]}, nil
+	})
+}
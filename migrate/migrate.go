@@ -0,0 +1,406 @@
+// Package migrate runs ordered, versioned migrations against an Appwrite project.
+//
+// Applied migrations are recorded as documents of a bookkeeping collection, so each
+// migration runs once per project:
+//
+//	r := migrate.New(databases, "app",
+//		migrate.Migration{
+//			ID:   "20240501-add-views",
+//			Up:   func(ctx context.Context, db *gowrite.DatabaseService) error { ... },
+//			Down: func(ctx context.Context, db *gowrite.DatabaseService) error { ... },
+//		},
+//	)
+//	applied, err := r.Up(ctx)
+//
+// A lock document keeps concurrent runners, e.g. parallel deploys, from applying the
+// same migrations twice.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/query"
+)
+
+// DefaultCollectionID is the bookkeeping collection used when Runner.CollectionID is empty.
+const DefaultCollectionID = "gowrite_migrations"
+
+// lockID is the ID of the lock document; migrations cannot use it.
+const lockID = "lock"
+
+// ErrLocked is returned when another runner holds the migration lock.
+var ErrLocked = errors.New("migrate: migrations are locked by another runner")
+
+// validID matches the document IDs Appwrite accepts.
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,35}$`)
+
+// Migration is a versioned change. Migrations run in ascending order of their IDs, so
+// IDs usually start with a timestamp or a zero padded number.
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, db *gowrite.DatabaseService) error
+	// Down reverts Up; migrations without Down cannot be rolled back.
+	Down func(ctx context.Context, db *gowrite.DatabaseService) error
+}
+
+func (m Migration) String() string {
+	if m.Description == "" {
+		return m.ID
+	}
+	return m.ID + " " + m.Description
+}
+
+// Status reports whether a migration has been applied.
+type Status struct {
+	ID          string
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+}
+
+// Runner applies and reverts migrations.
+type Runner struct {
+	DB *gowrite.DatabaseService
+	// DatabaseID is the database holding the bookkeeping collection.
+	DatabaseID string
+	// CollectionID is the bookkeeping collection, DefaultCollectionID when empty. It is
+	// created on first use.
+	CollectionID string
+	// DryRun reports what would run without changing anything.
+	DryRun bool
+	// Out receives a line per migration run or, in dry-run mode, per migration that
+	// would run. Nil discards the output.
+	Out io.Writer
+	// Owner identifies the runner in the lock document; it defaults to host and pid.
+	Owner string
+	// LockTTL is the time since the lock was last renewed after which it is considered
+	// abandoned and taken over; zero means 15 minutes. The lock is renewed before every
+	// migration, so LockTTL has to exceed the longest single migration.
+	LockTTL time.Duration
+
+	migrations []Migration
+	// lockRevision is the $updatedAt of the lock document as last written by the
+	// runner, and zero while it does not hold the lock.
+	lockRevision time.Time
+}
+
+// New returns a runner for migrations keeping its records in databaseID.
+func New(db *gowrite.DatabaseService, databaseID string, migrations ...Migration) *Runner {
+	r := &Runner{DB: db, DatabaseID: databaseID}
+	r.Add(migrations...)
+	return r
+}
+
+// Add registers migrations.
+func (r *Runner) Add(migrations ...Migration) {
+	r.migrations = append(r.migrations, migrations...)
+	slices.SortFunc(r.migrations, func(a, b Migration) int { return strings.Compare(a.ID, b.ID) })
+}
+
+func (r *Runner) collectionID() string {
+	if r.CollectionID != "" {
+		return r.CollectionID
+	}
+	return DefaultCollectionID
+}
+
+func (r *Runner) printf(format string, args ...interface{}) {
+	if r.Out != nil {
+		fmt.Fprintf(r.Out, format+"\n", args...)
+	}
+}
+
+func (r *Runner) validate() error {
+	for i, m := range r.migrations {
+		if !validID.MatchString(m.ID) || m.ID == lockID {
+			return fmt.Errorf("migrate: invalid migration id %q", m.ID)
+		}
+		if i > 0 && r.migrations[i-1].ID == m.ID {
+			return fmt.Errorf("migrate: duplicate migration id %q", m.ID)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migrate: migration %s has no Up", m.ID)
+		}
+	}
+	return nil
+}
+
+// Status lists the registered migrations and whether they have been applied.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, len(r.migrations))
+	for i, m := range r.migrations {
+		at, ok := applied[m.ID]
+		out[i] = Status{ID: m.ID, Description: m.Description, Applied: ok, AppliedAt: at}
+	}
+	return out, nil
+}
+
+// Up applies all pending migrations in order and returns their IDs. It stops at the
+// first failing migration; the ones before it stay applied.
+func (r *Runner) Up(ctx context.Context) ([]string, error) {
+	return r.run(ctx, func(applied map[string]time.Time) ([]string, error) {
+		var done []string
+		for _, m := range r.migrations {
+			if _, ok := applied[m.ID]; ok {
+				continue
+			}
+			if r.DryRun {
+				r.printf("would apply %s", m)
+				done = append(done, m.ID)
+				continue
+			}
+			if err := r.refreshLock(ctx); err != nil {
+				return done, err
+			}
+			r.printf("applying %s", m)
+			if err := m.Up(ctx, r.DB); err != nil {
+				return done, fmt.Errorf("migrate: %s: %w", m.ID, err)
+			}
+			data := map[string]interface{}{
+				"description": m.Description,
+				"appliedAt":   time.Now().UTC().Format(time.RFC3339Nano),
+			}
+			if _, err := r.DB.CreateDocumentCtx(ctx, r.DatabaseID, r.collectionID(), m.ID, data, nil); err != nil {
+				return done, fmt.Errorf("migrate: recording %s: %w", m.ID, err)
+			}
+			done = append(done, m.ID)
+		}
+		return done, nil
+	})
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns their IDs.
+func (r *Runner) Down(ctx context.Context, steps int) ([]string, error) {
+	return r.run(ctx, func(applied map[string]time.Time) ([]string, error) {
+		var done []string
+		for i := len(r.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := applied[m.ID]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return done, fmt.Errorf("migrate: %s cannot be reverted", m.ID)
+			}
+			if r.DryRun {
+				r.printf("would revert %s", m)
+				done = append(done, m.ID)
+				continue
+			}
+			if err := r.refreshLock(ctx); err != nil {
+				return done, err
+			}
+			r.printf("reverting %s", m)
+			if err := m.Down(ctx, r.DB); err != nil {
+				return done, fmt.Errorf("migrate: %s: %w", m.ID, err)
+			}
+			if err := r.DB.DeleteDocumentCtx(ctx, r.DatabaseID, r.collectionID(), m.ID); err != nil {
+				return done, fmt.Errorf("migrate: unrecording %s: %w", m.ID, err)
+			}
+			done = append(done, m.ID)
+		}
+		return done, nil
+	})
+}
+
+// run prepares the bookkeeping collection, takes the lock unless in dry-run mode and
+// calls fn with the applied migrations.
+func (r *Runner) run(ctx context.Context, fn func(applied map[string]time.Time) ([]string, error)) (_ []string, err error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	if !r.DryRun {
+		if err := r.ensureCollection(ctx); err != nil {
+			return nil, err
+		}
+		if err := r.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if unlockErr := r.unlock(context.WithoutCancel(ctx)); err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fn(applied)
+}
+
+// applied returns the recorded migrations with their application time. A missing
+// bookkeeping collection means that nothing has been applied.
+func (r *Runner) applied(ctx context.Context) (map[string]time.Time, error) {
+	applied := make(map[string]time.Time)
+	queries := []string{query.NotEqual("$id", lockID)}
+	for doc, err := range r.DB.IterateDocuments(ctx, r.DatabaseID, r.collectionID(), queries) {
+		if gowrite.IsNotFound(err) {
+			return applied, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		at, _ := time.Parse(time.RFC3339Nano, fmt.Sprint(doc.Data["appliedAt"]))
+		applied[doc.ID] = at
+	}
+	return applied, nil
+}
+
+// ensureCollection creates the bookkeeping collection when it does not exist.
+func (r *Runner) ensureCollection(ctx context.Context) error {
+	_, err := r.DB.GetCollectionCtx(ctx, r.DatabaseID, r.collectionID())
+	if !gowrite.IsNotFound(err) {
+		return err
+	}
+
+	if _, err := r.DB.CreateCollectionCtx(ctx, r.DatabaseID, r.collectionID(), "Migrations", nil, false, true); err != nil && !gowrite.IsConflict(err) {
+		return err
+	}
+	attrs := []gowrite.TypedAttribute{
+		&gowrite.StringAttribute{Attribute: gowrite.Attribute{Key: "description"}, Size: 1024},
+		&gowrite.DatetimeAttribute{Attribute: gowrite.Attribute{Key: "appliedAt"}},
+		&gowrite.StringAttribute{Attribute: gowrite.Attribute{Key: "owner"}, Size: 255},
+	}
+	for _, a := range attrs {
+		if _, err := r.DB.CreateTypedAttributeCtx(ctx, r.DatabaseID, r.collectionID(), a); err != nil && !gowrite.IsConflict(err) {
+			return err
+		}
+	}
+	_, err = r.DB.WaitForCollectionReady(ctx, r.DatabaseID, r.collectionID())
+	return err
+}
+
+func (r *Runner) owner() string {
+	if r.Owner != "" {
+		return r.Owner
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+func (r *Runner) lockTTL() time.Duration {
+	if r.LockTTL > 0 {
+		return r.LockTTL
+	}
+	return 15 * time.Minute
+}
+
+// lock creates the lock document, which fails while another runner holds it. A lock
+// not renewed within LockTTL is taken over by rewriting it on the condition that it is
+// still at the revision that was found stale, so that of several runners finding the
+// same abandoned lock only one gets it.
+func (r *Runner) lock(ctx context.Context) error {
+	data := map[string]interface{}{
+		"owner":     r.owner(),
+		"appliedAt": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := r.DB.CreateDocumentCtx(ctx, r.DatabaseID, r.collectionID(), lockID, data, nil)
+		if err == nil {
+			r.lockRevision = created.UpdatedAt
+			return nil
+		}
+		if !gowrite.IsConflict(err) {
+			return err
+		}
+
+		held := &gowrite.Document{ID: lockID, Database: r.DatabaseID, Collection: r.collectionID()}
+		taken, err := r.lockDB().UpdateDocumentIfUnchangedCtx(ctx, held, func(d *gowrite.Document) error {
+			if time.Since(d.UpdatedAt) < r.lockTTL() {
+				return fmt.Errorf("%w (held by %v since %s)", ErrLocked, d.Data["owner"], d.CreatedAt.Format(time.RFC3339))
+			}
+			r.printf("taking over lock of %v last renewed at %s", d.Data["owner"], d.UpdatedAt.Format(time.RFC3339))
+			d.Data = data
+			return nil
+		})
+		switch {
+		case gowrite.IsNotFound(err):
+			// Released in the meantime; create it again.
+			continue
+		case err != nil:
+			var ce *gowrite.ConflictError
+			if errors.As(err, &ce) {
+				// Other runners keep changing the lock, so it is not abandoned.
+				return fmt.Errorf("%w (%v)", ErrLocked, err)
+			}
+			return err
+		}
+		r.lockRevision = taken.UpdatedAt
+		return nil
+	}
+	return ErrLocked
+}
+
+// lockDB returns the service used for the lock document. It bypasses the cache, so
+// that every read sees the latest renewal, and compares revisions on $updatedAt, as
+// the lock has no version attribute.
+func (r *Runner) lockDB() *gowrite.DatabaseService {
+	db := *r.DB
+	db.Cache, db.VersionAttribute = nil, ""
+	return &db
+}
+
+// refreshLock renews the lock so that a long run is not taken over as abandoned. It
+// returns ErrLocked when another runner took the lock over in the meantime.
+func (r *Runner) refreshLock(ctx context.Context) error {
+	held := &gowrite.Document{ID: lockID, Database: r.DatabaseID, Collection: r.collectionID()}
+	renewed, err := r.lockDB().UpdateDocumentIfUnchangedCtx(ctx, held, func(d *gowrite.Document) error {
+		if !d.UpdatedAt.Equal(r.lockRevision) {
+			return fmt.Errorf("%w (taken over by %v)", ErrLocked, d.Data["owner"])
+		}
+		d.Data = map[string]interface{}{"appliedAt": time.Now().UTC().Format(time.RFC3339Nano)}
+		return nil
+	})
+	if gowrite.IsNotFound(err) {
+		err = fmt.Errorf("%w (lock was removed)", ErrLocked)
+	}
+	if err != nil {
+		if errors.Is(err, ErrLocked) {
+			// The lock belongs to someone else now; unlock must leave it alone.
+			r.lockRevision = time.Time{}
+		}
+		return err
+	}
+	r.lockRevision = renewed.UpdatedAt
+	return nil
+}
+
+// unlock deletes the lock unless another runner has taken it over since it was last
+// renewed.
+func (r *Runner) unlock(ctx context.Context) error {
+	if r.lockRevision.IsZero() {
+		return nil
+	}
+	revision := r.lockRevision
+	r.lockRevision = time.Time{}
+	held, err := r.lockDB().GetDocumentCtx(ctx, r.DatabaseID, r.collectionID(), lockID)
+	switch {
+	case gowrite.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	case !held.UpdatedAt.Equal(revision):
+		// Taken over after the last renewal.
+		return nil
+	}
+	err = r.DB.DeleteDocumentCtx(ctx, r.DatabaseID, r.collectionID(), lockID)
+	if gowrite.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,320 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/migrate"
+)
+
+// stamp formats t as Appwrite datetimes are stored.
+func stamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000+00:00")
+}
+
+// matches evaluates the equal queries of a conditional update against doc.
+func matches(doc map[string]interface{}, queries []string) bool {
+	for _, raw := range queries {
+		var q struct {
+			Method    string        `json:"method"`
+			Attribute string        `json:"attribute"`
+			Values    []interface{} `json:"values"`
+		}
+		json.Unmarshal([]byte(raw), &q)
+		if q.Method == "equal" && fmt.Sprint(doc[q.Attribute]) != fmt.Sprint(q.Values[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// bookkeeping serves an existing migrations collection whose documents live in memory,
+// as an Appwrite 1.7 server with conditional bulk updates. The lock document is not
+// returned by listings, mirroring the runner's notEqual query.
+//
+// With slowRead, the first two reads of the lock overlap: the second is answered with
+// the lock as it was when the read arrived, but only after the first reader rewrote it,
+// like a read that is slow on the way back.
+func bookkeeping(t *testing.T, docs map[string]map[string]interface{}, slowRead bool) *gowrite.DatabaseService {
+	var (
+		mu      sync.Mutex
+		last    time.Time
+		reads   atomic.Int32
+		arrived = make(chan struct{})
+		written = make(chan struct{})
+		wrote   sync.Once
+	)
+	lockWritten := func(id string) {
+		if id == "lock" {
+			wrote.Do(func() { close(written) })
+		}
+	}
+	const base = "/v1/databases/app/collections/gowrite_migrations"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, base+"/documents/")
+		if r.Method == "GET" && id == "lock" && slowRead {
+			switch reads.Add(1) {
+			case 1:
+				<-arrived
+			case 2:
+				mu.Lock()
+				snapshot := maps.Clone(docs["lock"])
+				mu.Unlock()
+				close(arrived)
+				select {
+				case <-written:
+				case <-time.After(2 * time.Second):
+				}
+				json.NewEncoder(w).Encode(snapshot)
+				return
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		// Every write gets a distinct $updatedAt, as conditional updates rely on it.
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		if !now.After(last) {
+			now = last.Add(time.Millisecond)
+		}
+		last = now
+		switch {
+		case r.URL.Path == "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.7.0"}`)
+		case r.URL.Path == base:
+			fmt.Fprint(w, `{"$id":"gowrite_migrations","attributes":[],"indexes":[]}`)
+		case r.Method == "GET" && r.URL.Path == base+"/documents":
+			var list []map[string]interface{}
+			for _, id := range slices.Sorted(maps.Keys(docs)) {
+				if id != "lock" {
+					list = append(list, docs[id])
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"total": len(list), "documents": list})
+		case r.Method == "PATCH" && r.URL.Path == base+"/documents":
+			var body struct {
+				Data    map[string]interface{} `json:"data"`
+				Queries []string               `json:"queries"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			list := []map[string]interface{}{}
+			for _, id := range slices.Sorted(maps.Keys(docs)) {
+				if matches(docs[id], body.Queries) {
+					maps.Copy(docs[id], body.Data)
+					docs[id]["$updatedAt"] = stamp(now)
+					list = append(list, docs[id])
+					lockWritten(id)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"total": len(list), "documents": list})
+		case r.Method == "POST":
+			var body struct {
+				DocumentID string                 `json:"documentId"`
+				Data       map[string]interface{} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if docs[body.DocumentID] != nil {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"message":"exists","code":409,"type":"document_already_exists"}`)
+				return
+			}
+			body.Data["$id"] = body.DocumentID
+			body.Data["$createdAt"], body.Data["$updatedAt"] = stamp(now), stamp(now)
+			docs[body.DocumentID] = body.Data
+			lockWritten(body.DocumentID)
+			json.NewEncoder(w).Encode(body.Data)
+		case r.Method == "GET" && docs[id] != nil:
+			json.NewEncoder(w).Encode(docs[id])
+		case r.Method == "DELETE" && docs[id] != nil:
+			delete(docs, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found","code":404,"type":"document_not_found"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+}
+
+func TestRunner(t *testing.T) {
+	docs := make(map[string]map[string]interface{})
+	db := bookkeeping(t, docs, false)
+	ctx := context.Background()
+
+	var ran []string
+	step := func(name string) func(context.Context, *gowrite.DatabaseService) error {
+		return func(context.Context, *gowrite.DatabaseService) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	r := migrate.New(db, "app",
+		migrate.Migration{ID: "002-backfill", Up: step("up 2"), Down: step("down 2")},
+		migrate.Migration{ID: "001-add", Up: step("up 1"), Down: step("down 1")},
+	)
+
+	var out bytes.Buffer
+	r.DryRun, r.Out = true, &out
+	pending, err := r.Up(ctx)
+	if err != nil || !slices.Equal(pending, []string{"001-add", "002-backfill"}) || len(ran) != 0 {
+		t.Fatalf("dry run = %v, %v; ran %v", pending, err, ran)
+	}
+	if out.String() != "would apply 001-add\nwould apply 002-backfill\n" {
+		t.Fatalf("dry run output %q", out.String())
+	}
+
+	r.DryRun = false
+	if _, err := r.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if applied, err := r.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up = %v, %v", applied, err)
+	}
+	if docs["lock"] != nil {
+		t.Fatal("lock was not released")
+	}
+
+	reverted, err := r.Down(ctx, 1)
+	if err != nil || !slices.Equal(reverted, []string{"002-backfill"}) {
+		t.Fatalf("Down = %v, %v", reverted, err)
+	}
+	if want := []string{"up 1", "up 2", "down 2"}; !slices.Equal(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+
+	status, err := r.Status(ctx)
+	if err != nil || len(status) != 2 || !status[0].Applied || status[1].Applied {
+		t.Fatalf("Status = %+v, %v", status, err)
+	}
+}
+
+func TestRunnerLocked(t *testing.T) {
+	docs := map[string]map[string]interface{}{
+		"lock": {"$id": "lock", "owner": "deploy-1", "$updatedAt": stamp(time.Now())},
+	}
+	db := bookkeeping(t, docs, false)
+	ran := false
+	r := migrate.New(db, "app", migrate.Migration{ID: "001", Up: func(context.Context, *gowrite.DatabaseService) error {
+		ran = true
+		return nil
+	}})
+
+	if _, err := r.Up(context.Background()); !errors.Is(err, migrate.ErrLocked) || ran {
+		t.Fatalf("Up = %v (ran %v), want ErrLocked", err, ran)
+	}
+
+	// An abandoned lock is taken over.
+	docs["lock"]["$updatedAt"] = stamp(time.Now().Add(-time.Hour))
+	r.LockTTL = time.Minute
+	if applied, err := r.Up(context.Background()); err != nil || len(applied) != 1 || !ran {
+		t.Fatalf("Up after stale lock = %v, %v", applied, err)
+	}
+}
+
+func TestRunnerRenewsLock(t *testing.T) {
+	docs := make(map[string]map[string]interface{})
+	db := bookkeeping(t, docs, false)
+	var renewed []interface{}
+	record := func(context.Context, *gowrite.DatabaseService) error {
+		renewed = append(renewed, docs["lock"]["appliedAt"])
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	r := migrate.New(db, "app",
+		migrate.Migration{ID: "001", Up: record},
+		migrate.Migration{ID: "002", Up: record},
+	)
+	if _, err := r.Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(renewed) != 2 || renewed[0] == renewed[1] {
+		t.Fatalf("lock renewals seen by the migrations = %v", renewed)
+	}
+
+	// Another runner takes the lock over while the first migration runs.
+	ran := false
+	r = migrate.New(db, "app",
+		migrate.Migration{ID: "003", Up: func(context.Context, *gowrite.DatabaseService) error {
+			now := stamp(time.Now().Add(time.Second))
+			docs["lock"] = map[string]interface{}{"$id": "lock", "owner": "deploy-2", "$createdAt": now, "$updatedAt": now}
+			return nil
+		}},
+		migrate.Migration{ID: "004", Up: func(context.Context, *gowrite.DatabaseService) error {
+			ran = true
+			return nil
+		}},
+	)
+	applied, err := r.Up(context.Background())
+	if !errors.Is(err, migrate.ErrLocked) || ran || !slices.Equal(applied, []string{"003"}) {
+		t.Fatalf("Up after takeover = %v, %v (004 ran %v)", applied, err, ran)
+	}
+	if docs["lock"]["owner"] != "deploy-2" {
+		t.Fatal("the lock of the other runner was released")
+	}
+}
+
+func TestRunnerLockTakeoverRace(t *testing.T) {
+	stale := stamp(time.Now().Add(-time.Hour))
+	docs := map[string]map[string]interface{}{
+		"lock": {"$id": "lock", "owner": "crashed", "$createdAt": stale, "$updatedAt": stale},
+	}
+	db := bookkeeping(t, docs, true)
+	var deletes atomic.Int32
+	db.Client.Use(func(next gowrite.Handler) gowrite.Handler {
+		return func(req *gowrite.Request) (*gowrite.Response, error) {
+			if req.HTTP.Method == http.MethodDelete && strings.HasSuffix(req.HTTP.URL.Path, "/documents/lock") {
+				deletes.Add(1)
+			}
+			return next(req)
+		}
+	})
+
+	// Both runners find the abandoned lock; one of them sees it only after the other
+	// has taken it over.
+	var (
+		runs atomic.Int32
+		wg   sync.WaitGroup
+		errs = make([]error, 2)
+	)
+	for i, owner := range []string{"deploy-a", "deploy-b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := migrate.New(db, "app", migrate.Migration{ID: "001", Up: func(context.Context, *gowrite.DatabaseService) error {
+				runs.Add(1)
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			}})
+			r.Owner, r.LockTTL = owner, time.Minute
+			_, errs[i] = r.Up(context.Background())
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, migrate.ErrLocked) {
+			t.Fatalf("Up = %v", err)
+		}
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("migration ran %d times after a concurrent takeover, want 1 (errors %v)", n, errs)
+	}
+	// Only the winner's unlock may delete the lock; the other runner must not remove
+	// a lock it observed before the takeover.
+	if n := deletes.Load(); n != 1 {
+		t.Fatalf("lock deleted %d times, want 1 (errors %v)", n, errs)
+	}
+}
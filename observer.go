@@ -0,0 +1,35 @@
+package gowrite
+
+import (
+	"context"
+)
+
+// Observer receives events that do not map to a single HTTP request, such as listings
+// fetched page by page and cache lookups. Instrumentation packages implement it next
+// to a Middleware; see the otelgowrite module.
+type Observer interface {
+	// StartOperation is called when a call spanning several requests starts. The
+	// requests are sent with the returned context and end is called with the result.
+	StartOperation(ctx context.Context, op Operation) (context.Context, func(err error))
+	// CacheLookup reports whether a cached result for op was found.
+	CacheLookup(ctx context.Context, op Operation, hit bool)
+}
+
+// WithObserver registers o to receive operation and cache events.
+func (client *AppwriteClient) WithObserver(o Observer) *AppwriteClient {
+	client.Observer = o
+	return client
+}
+
+func (client *AppwriteClient) startOperation(ctx context.Context, op Operation) (context.Context, func(error)) {
+	if client.Observer == nil {
+		return ctx, func(error) {}
+	}
+	return client.Observer.StartOperation(ctx, op)
+}
+
+func (client *AppwriteClient) cacheLookup(ctx context.Context, op Operation, hit bool) {
+	if client.Observer != nil {
+		client.Observer.CacheLookup(ctx, op, hit)
+	}
+}
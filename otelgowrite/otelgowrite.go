@@ -0,0 +1,229 @@
+// Package otelgowrite instruments a gowrite client with OpenTelemetry tracing and
+// metrics. It lives in its own module so that the core client does not depend on the
+// OpenTelemetry SDK.
+//
+//	client := gowrite.NewClient(endpoint, project, key)
+//	if err := otelgowrite.Instrument(client); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Every logical API call produces a client span named after the operation, e.g.
+// "databases.getDocument", carrying the resource IDs as attributes. Calls spanning
+// several requests, such as ListDocuments, get a parent span with a child span for
+// every page. Cache lookups of DatabaseService are recorded as span events.
+package otelgowrite
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dm-vev/gowrite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/dm-vev/gowrite/otelgowrite"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithTracerProvider sets the provider used to create spans. The global provider is
+// used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// WithMeterProvider sets the provider used to record metrics. The global provider is
+// used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) { c.meterProvider = mp }
+}
+
+// WithPropagator sets the propagator injecting trace context into request headers.
+// The global propagator is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagator = p }
+}
+
+// Instrumentation records spans and metrics for a gowrite client. It implements
+// gowrite.Observer and provides the matching Middleware.
+type Instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	duration     metric.Float64Histogram
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+	cacheLookups metric.Int64Counter
+}
+
+// New creates an Instrumentation without attaching it to a client.
+func New(opts ...Option) (*Instrumentation, error) {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meter := cfg.meterProvider.Meter(instrumentationName)
+	inst := &Instrumentation{
+		tracer:     cfg.tracerProvider.Tracer(instrumentationName),
+		propagator: cfg.propagator,
+	}
+
+	var err error
+	if inst.duration, err = meter.Float64Histogram("gowrite.client.operation.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of Appwrite API calls.")); err != nil {
+		return nil, err
+	}
+	if inst.requestSize, err = meter.Int64Histogram("gowrite.client.request.body.size",
+		metric.WithUnit("By"), metric.WithDescription("Size of Appwrite request payloads.")); err != nil {
+		return nil, err
+	}
+	if inst.responseSize, err = meter.Int64Histogram("gowrite.client.response.body.size",
+		metric.WithUnit("By"), metric.WithDescription("Size of Appwrite response payloads.")); err != nil {
+		return nil, err
+	}
+	if inst.cacheLookups, err = meter.Int64Counter("gowrite.client.cache.lookups",
+		metric.WithDescription("DatabaseService cache lookups by result.")); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// Instrument creates an Instrumentation and installs it on client as both middleware
+// and observer.
+func Instrument(client *gowrite.AppwriteClient, opts ...Option) error {
+	inst, err := New(opts...)
+	if err != nil {
+		return err
+	}
+	client.Use(inst.Middleware())
+	client.WithObserver(inst)
+	return nil
+}
+
+// Middleware returns a gowrite.Middleware creating a span per API call and recording
+// latency and payload sizes.
+func (inst *Instrumentation) Middleware() gowrite.Middleware {
+	return func(next gowrite.Handler) gowrite.Handler {
+		return func(req *gowrite.Request) (*gowrite.Response, error) {
+			ctx, span := inst.tracer.Start(req.HTTP.Context(), req.Operation.Name(),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(operationAttributes(req.Operation)...),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.HTTP.Method),
+					attribute.String("url.path", req.HTTP.URL.Path),
+				),
+			)
+			defer span.End()
+
+			req.HTTP = req.HTTP.WithContext(ctx)
+			inst.propagator.Inject(ctx, propagation.HeaderCarrier(req.HTTP.Header))
+
+			start := time.Now()
+			resp, err := next(req)
+			elapsed := time.Since(start).Seconds()
+
+			// Resource IDs stay on the span only, metric attributes must be low-cardinality.
+			attrs := []attribute.KeyValue{
+				attribute.String("appwrite.service", req.Operation.Service),
+				attribute.String("appwrite.operation", req.Operation.Name()),
+			}
+			if resp != nil {
+				span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+				attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+				inst.responseSize.Record(ctx, resp.BodySize, metric.WithAttributes(attrs...))
+			}
+			if req.HTTP.ContentLength > 0 {
+				inst.requestSize.Record(ctx, req.HTTP.ContentLength, metric.WithAttributes(attrs...))
+			}
+			if err != nil {
+				recordError(span, err)
+				if appErr, ok := gowrite.AsAppwriteError(err); ok && appErr.Type != "" {
+					attrs = append(attrs, attribute.String("appwrite.error.type", appErr.Type))
+				}
+			}
+			inst.duration.Record(ctx, elapsed, metric.WithAttributes(attrs...))
+			return resp, err
+		}
+	}
+}
+
+// StartOperation starts a parent span for calls made of several requests.
+func (inst *Instrumentation) StartOperation(ctx context.Context, op gowrite.Operation) (context.Context, func(error)) {
+	ctx, span := inst.tracer.Start(ctx, op.Name(),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(operationAttributes(op)...),
+	)
+	return ctx, func(err error) {
+		if err != nil {
+			recordError(span, err)
+		}
+		span.End()
+	}
+}
+
+// CacheLookup adds a cache event to the current span and counts the lookup.
+func (inst *Instrumentation) CacheLookup(ctx context.Context, op gowrite.Operation, hit bool) {
+	name := "cache.miss"
+	if hit {
+		name = "cache.hit"
+	}
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(attribute.String("appwrite.operation", op.Name())))
+	inst.cacheLookups.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("appwrite.operation", op.Name()),
+		attribute.Bool("appwrite.cache.hit", hit),
+	))
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	if appErr, ok := gowrite.AsAppwriteError(err); ok && appErr.Type != "" {
+		span.SetAttributes(attribute.String("appwrite.error.type", appErr.Type))
+	}
+}
+
+// operationAttributes converts the operation and its params into attributes, e.g.
+// "databaseId" becomes "appwrite.database_id".
+func operationAttributes(op gowrite.Operation) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(op.Params)+2)
+	attrs = append(attrs,
+		attribute.String("appwrite.service", op.Service),
+		attribute.String("appwrite.operation", op.Name()),
+	)
+	for k, v := range op.Params {
+		attrs = append(attrs, attribute.String("appwrite."+snakeCase(k), v))
+	}
+	return attrs
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
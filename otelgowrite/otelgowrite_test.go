@@ -0,0 +1,123 @@
+package otelgowrite_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/otelgowrite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestListDocumentsSpans(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Traceparent") == "" {
+			t.Errorf("trace context not propagated")
+		}
+		fmt.Fprint(w, `{"total":1,"documents":[{"$id":"doc","title":"hello"}]}`)
+	}))
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client := gowrite.NewClient(srv.URL, "project", "key")
+	if err := otelgowrite.Instrument(client,
+		otelgowrite.WithTracerProvider(tp),
+		otelgowrite.WithMeterProvider(mp),
+		otelgowrite.WithPropagator(propagation.TraceContext{}),
+	); err != nil {
+		t.Fatalf("Instrument: %v", err)
+	}
+
+	if _, err := gowrite.NewDatabases(client).ListDocuments("db", "col", nil); err != nil {
+		t.Fatalf("ListDocuments: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) < 2 {
+		t.Fatalf("got %d spans, want parent and page spans", len(spans))
+	}
+	parent := spans[len(spans)-1]
+	if parent.Name() != "databases.listDocuments" || parent.Parent().IsValid() {
+		t.Fatalf("unexpected parent span %q", parent.Name())
+	}
+	for _, page := range spans[:len(spans)-1] {
+		if page.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("page span %q is not a child of the listing", page.Name())
+		}
+		if !hasAttribute(page.Attributes(), attribute.String("appwrite.collection_id", "col")) {
+			t.Errorf("page span misses the collection id: %v", page.Attributes())
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	found := false
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "gowrite.client.operation.duration" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatal("duration histogram not recorded")
+	}
+}
+
+func TestStreamedDownloadSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "file content")
+	}))
+	defer srv.Close()
+
+	reader := sdkmetric.NewManualReader()
+	client := gowrite.NewClient(srv.URL, "project", "key")
+	if err := otelgowrite.Instrument(client, otelgowrite.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))); err != nil {
+		t.Fatalf("Instrument: %v", err)
+	}
+	if _, err := gowrite.NewStorage(client).DownloadFileTo("bucket", "file", io.Discard); err != nil {
+		t.Fatalf("DownloadFileTo: %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "gowrite.client.response.body.size" {
+				continue
+			}
+			points := m.Data.(metricdata.Histogram[int64]).DataPoints
+			if len(points) != 1 || points[0].Sum != 12 {
+				t.Fatalf("response size points = %+v, want one of 12 bytes", points)
+			}
+			return
+		}
+	}
+	t.Fatal("response size histogram not recorded")
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package gowrite
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Statuses of attributes and indexes while the server provisions them.
+const (
+	StatusAvailable  = "available"
+	StatusProcessing = "processing"
+	StatusDeleting   = "deleting"
+	StatusStuck      = "stuck"
+	StatusFailed     = "failed"
+)
+
+// Polling intervals used while waiting for attributes and indexes.
+const (
+	pollInitialDelay = 100 * time.Millisecond
+	pollMaxDelay     = 2 * time.Second
+)
+
+// ProvisioningError is returned when an attribute or index ends up failed or stuck
+// instead of available.
+type ProvisioningError struct {
+	// Kind is "attribute" or "index".
+	Kind    string
+	Key     string
+	Status  string
+	Message string
+}
+
+func (e *ProvisioningError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %q is %s", e.Kind, e.Key, e.Status)
+	}
+	return fmt.Sprintf("%s %q is %s: %s", e.Kind, e.Key, e.Status, e.Message)
+}
+
+// checkProvisioning returns a *ProvisioningError for failed and stuck statuses and
+// reports whether status is available.
+func checkProvisioning(kind, key, status, message string) (bool, error) {
+	switch status {
+	case StatusAvailable:
+		return true, nil
+	case StatusFailed, StatusStuck:
+		return false, &ProvisioningError{Kind: kind, Key: key, Status: status, Message: message}
+	}
+	return false, nil
+}
+
+// poll calls check with growing delays until it reports done or fails. When ctx ends
+// first the error names what was still pending.
+func poll(ctx context.Context, check func() (done bool, pending string, err error)) error {
+	for delay := pollInitialDelay; ; delay = min(delay*2, pollMaxDelay) {
+		done, pending, err := check()
+		if err != nil || done {
+			return err
+		}
+		if err := sleepCtx(ctx, delay); err != nil {
+			return fmt.Errorf("waiting for %s: %w", pending, err)
+		}
+	}
+}
+
+// WaitForIndex polls the index until it is available and returns it. It returns a
+// *ProvisioningError when the index failed or got stuck, and ctx's error when ctx
+// ends first, so callers bound the wait with a context deadline. An index being
+// deleted is polled until it is gone, and then the server's not-found error is
+// returned; check for it with IsNotFound.
+func (db *DatabaseService) WaitForIndex(ctx context.Context, databaseID, collectionID, key string) (index *Index, err error) {
+	err = poll(ctx, func() (bool, string, error) {
+		if index, err = db.GetIndexCtx(ctx, databaseID, collectionID, key); err != nil {
+			return false, "", err
+		}
+		done, err := checkProvisioning("index", key, index.Status, index.Error)
+		return done, fmt.Sprintf("index %q (%s)", key, index.Status), err
+	})
+	return index, err
+}
+
+// WaitForAttribute polls the attribute until it is available and returns it. It
+// returns a *ProvisioningError with the server's message when the attribute failed
+// or got stuck, and ctx's error when ctx ends first. Like WaitForIndex it returns the
+// server's not-found error once an attribute being deleted is gone.
+func (db *DatabaseService) WaitForAttribute(ctx context.Context, databaseID, collectionID, key string) (attr TypedAttribute, err error) {
+	err = poll(ctx, func() (bool, string, error) {
+		if attr, err = db.GetTypedAttributeCtx(ctx, databaseID, collectionID, key); err != nil {
+			return false, "", err
+		}
+		base := attr.Base()
+		done, err := checkProvisioning("attribute", key, base.Status, base.Error)
+		return done, fmt.Sprintf("attribute %q (%s)", key, base.Status), err
+	})
+	return attr, err
+}
+
+// WaitForCollectionReady polls the collection until all of its attributes and indexes
+// are available and returns it. Attributes and indexes being deleted, for example by
+// a concurrent schema change, are skipped. Like WaitForAttribute it fails on the first
+// failed or stuck attribute or index, and when ctx ends first.
+func (db *DatabaseService) WaitForCollectionReady(ctx context.Context, databaseID, collectionID string) (col *Collection, err error) {
+	err = poll(ctx, func() (bool, string, error) {
+		if col, err = db.GetCollectionCtx(ctx, databaseID, collectionID); err != nil {
+			return false, "", err
+		}
+		attrs, err := col.TypedAttributes()
+		if err != nil {
+			return false, "", err
+		}
+		indexes, err := col.TypedIndexes()
+		if err != nil {
+			return false, "", err
+		}
+		pending := ""
+		for _, a := range attrs {
+			base := a.Base()
+			if base.Status == StatusDeleting {
+				continue
+			}
+			done, err := checkProvisioning("attribute", base.Key, base.Status, base.Error)
+			if err != nil {
+				return false, "", err
+			}
+			if !done && pending == "" {
+				pending = fmt.Sprintf("attribute %q (%s)", base.Key, base.Status)
+			}
+		}
+		for _, idx := range indexes {
+			if idx.Status == StatusDeleting {
+				continue
+			}
+			done, err := checkProvisioning("index", idx.Key, idx.Status, idx.Error)
+			if err != nil {
+				return false, "", err
+			}
+			if !done && pending == "" {
+				pending = fmt.Sprintf("index %q (%s)", idx.Key, idx.Status)
+			}
+		}
+		return pending == "", pending, nil
+	})
+	return col, err
+}
@@ -241,3 +241,10 @@ func And(queries []string) string {
 		Values: &parsedQueries,
 	})
 }
+
+// Parse decodes a query produced by this package back into its options.
+func Parse(query string) (QueryOptions, error) {
+	var options QueryOptions
+	err := json.Unmarshal([]byte(query), &options)
+	return options, err
+}
@@ -0,0 +1,175 @@
+package gowrite
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter throttles the requests sent by an AppwriteClient. Wait blocks until a
+// request to the endpoint group (the first path segment, e.g. "databases") may be sent,
+// and Observe receives the headers of every response to adapt to the server's limits.
+type RateLimiter interface {
+	Wait(ctx context.Context, group string) error
+	Observe(group string, header http.Header)
+}
+
+// WithRateLimiter makes every request sent through the client wait on l.
+func (client *AppwriteClient) WithRateLimiter(l RateLimiter) *AppwriteClient {
+	client.Limiter = l
+	return client
+}
+
+// TokenBucket is a RateLimiter that allows Rate requests per second with bursts of up
+// to Burst requests. It ignores the group, so a single bucket is shared by all
+// endpoints; use GroupLimiter for separate budgets.
+type TokenBucket struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// NewTokenBucket creates a full bucket refilling at rate tokens per second.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// Wait takes a token, blocking until one is available or ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context, group string) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.refill(now)
+
+		var wait time.Duration
+		switch {
+		case now.Before(b.blockedUntil):
+			wait = b.blockedUntil.Sub(now)
+		case b.tokens >= 1:
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		case b.rate <= 0:
+			wait = time.Second
+		default:
+			wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Observe adapts the bucket to X-RateLimit-Remaining and X-RateLimit-Reset: the local
+// budget never exceeds what the server reports, and an exhausted window blocks the
+// bucket until the reset time.
+func (b *TokenBucket) Observe(group string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if float64(remaining) < b.tokens {
+		b.tokens = float64(remaining)
+	}
+	if remaining > 0 {
+		return
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if until := time.Unix(reset, 0); until.After(b.blockedUntil) {
+			b.blockedUntil = until
+		}
+	}
+}
+
+// GroupLimiter keeps a separate RateLimiter per endpoint group. Limiters for groups
+// without an explicit entry are created on first use by the factory passed to
+// NewGroupLimiter; a nil factory leaves those groups unthrottled.
+type GroupLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]RateLimiter
+	factory  func(group string) RateLimiter
+}
+
+// NewGroupLimiter creates a GroupLimiter using factory for unconfigured groups.
+func NewGroupLimiter(factory func(group string) RateLimiter) *GroupLimiter {
+	return &GroupLimiter{
+		limiters: make(map[string]RateLimiter),
+		factory:  factory,
+	}
+}
+
+// Set assigns l to the given group, e.g. "databases", "storage" or "users".
+func (g *GroupLimiter) Set(group string, l RateLimiter) *GroupLimiter {
+	g.mu.Lock()
+	g.limiters[group] = l
+	g.mu.Unlock()
+	return g
+}
+
+func (g *GroupLimiter) limiter(group string) RateLimiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	l, ok := g.limiters[group]
+	if !ok && g.factory != nil {
+		l = g.factory(group)
+		g.limiters[group] = l
+	}
+	return l
+}
+
+// Wait waits on the group's limiter.
+func (g *GroupLimiter) Wait(ctx context.Context, group string) error {
+	if l := g.limiter(group); l != nil {
+		return l.Wait(ctx, group)
+	}
+	return nil
+}
+
+// Observe forwards the headers to the group's limiter.
+func (g *GroupLimiter) Observe(group string, header http.Header) {
+	if l := g.limiter(group); l != nil {
+		l.Observe(group, header)
+	}
+}
+
+// endpointGroup returns the first segment after /v1 in an API URL path.
+func endpointGroup(urlPath string) string {
+	if i := strings.Index(urlPath, "/v1/"); i >= 0 {
+		urlPath = urlPath[i+len("/v1/"):]
+	}
+	urlPath = strings.TrimPrefix(urlPath, "/")
+	if i := strings.IndexByte(urlPath, '/'); i >= 0 {
+		return urlPath[:i]
+	}
+	return urlPath
+}
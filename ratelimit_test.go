@@ -0,0 +1,51 @@
+package gowrite
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEndpointGroup(t *testing.T) {
+	cases := map[string]string{
+		"/v1/databases/db/collections": "databases",
+		"/appwrite/v1/storage/buckets": "storage",
+		"/v1/users":                    "users",
+	}
+	for path, want := range cases {
+		if got := endpointGroup(path); got != want {
+			t.Errorf("endpointGroup(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestTokenBucketBurstAndRefill(t *testing.T) {
+	b := NewTokenBucket(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := b.Wait(ctx, "databases"); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Fatalf("third token granted after %v, expected refill delay", elapsed)
+	}
+}
+
+func TestTokenBucketObserveExhaustedWindow(t *testing.T) {
+	b := NewTokenBucket(1000, 10)
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "0")
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	b.Observe("databases", h)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx, "databases"); err == nil {
+		t.Fatal("expected Wait to fail while the server window is exhausted")
+	}
+}
@@ -0,0 +1,160 @@
+package gowrite
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries failed requests. A nil policy, or one
+// with MaxAttempts <= 1, makes exactly one attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed exponential delay. Server supplied delays
+	// (Retry-After, X-RateLimit-Reset) are honored as is.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay between consecutive attempts.
+	Multiplier float64
+	// Jitter randomly shortens each delay by up to this fraction (0..1).
+	Jitter float64
+	// RetryStatuses lists the HTTP statuses worth retrying.
+	RetryStatuses []int
+	// RetryNonIdempotent allows retrying POST and PATCH requests. 429 responses are
+	// always retried because the server rejected the request before handling it.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a policy with three attempts and exponential backoff
+// starting at 200ms, retrying 429 and 5xx gateway errors of idempotent requests.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetry configures the client to retry failed requests according to p.
+func (client *AppwriteClient) WithRetry(p *RetryPolicy) *AppwriteClient {
+	client.Retry = p
+	return client
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the delay before the given attempt (2 for the first retry).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-2))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * p.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryStatus(status int) bool {
+	for _, s := range p.RetryStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry decides whether a failed attempt may be repeated. err is the transport
+// error, or an *AppwriteError for error statuses.
+func (p *RetryPolicy) shouldRetry(req *http.Request, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	idempotent := p.RetryNonIdempotent || isIdempotent(req)
+	appErr, ok := AsAppwriteError(err)
+	if !ok {
+		return idempotent
+	}
+	if !p.retryStatus(appErr.StatusCode) {
+		return false
+	}
+	return idempotent || appErr.StatusCode == http.StatusTooManyRequests
+}
+
+// isIdempotent reports whether repeating req is safe, either by its method or because
+// the caller marked it with retryableKey.
+func isIdempotent(req *http.Request) bool {
+	if retryable, _ := req.Context().Value(retryableKey{}).(bool); retryable {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// serverDelay extracts the wait requested by the server via Retry-After or, when the
+// rate limit window is exhausted, X-RateLimit-Reset.
+func serverDelay(h http.Header, now time.Time) (time.Duration, bool) {
+	if h == nil {
+		return 0, false
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t.Sub(now), true
+		}
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0).Sub(now), true
+		}
+	}
+	return 0, false
+}
+
+// sleepCtx waits for d or until ctx is done. It fails fast when the deadline would
+// expire before the wait is over.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,522 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+)
+
+// Action is the kind of a planned change.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Kinds of objects a change applies to.
+const (
+	KindDatabase   = "database"
+	KindCollection = "collection"
+	KindAttribute  = "attribute"
+	KindIndex      = "index"
+)
+
+// phases order the changes so that every object exists before something depends on it.
+const (
+	phaseDatabase = iota
+	phaseCollection
+	phaseDeleteIndex
+	phaseDeleteAttribute
+	phaseAttribute
+	phaseRelationship
+	phaseIndex
+	phaseDeleteCollection
+)
+
+// Change is a single step of a plan. The desired state is set for creates and updates.
+type Change struct {
+	Action       Action
+	Kind         string
+	DatabaseID   string
+	CollectionID string
+	// Key is the attribute or index key.
+	Key string
+	// Reason describes what differs for updates and recreations.
+	Reason string
+
+	Database   *Database
+	Collection *Collection
+	Attribute  *Attribute
+	Index      *Index
+
+	phase int
+}
+
+func (c Change) String() string {
+	target := c.DatabaseID
+	if c.CollectionID != "" {
+		target += "/" + c.CollectionID
+	}
+	if c.Key != "" {
+		target += "." + c.Key
+	}
+	s := fmt.Sprintf("%s %s %s", c.Action, c.Kind, target)
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
+
+// Options tune how a plan is computed.
+type Options struct {
+	// Prune deletes collections, attributes and indexes of the schema's databases that
+	// the schema does not declare, and recreates attributes whose type changed. Both
+	// lose data, so they are off by default.
+	Prune bool
+}
+
+// Plan is the ordered list of changes that brings a project to a schema.
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the project already matches the schema.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Inspect reads the live state of the given databases, or of all databases when none
+// are given, into a schema.
+func Inspect(ctx context.Context, db *gowrite.DatabaseService, databaseIDs ...string) (*Schema, error) {
+	if len(databaseIDs) == 0 {
+		dbs, err := db.ListDatabasesCtx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dbs {
+			databaseIDs = append(databaseIDs, d.ID)
+		}
+	}
+
+	s := &Schema{}
+	for _, id := range databaseIDs {
+		d, err := db.GetDatabaseCtx(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sd := Database{ID: d.ID, Name: d.Name, Enabled: ptr(d.Enabled)}
+		for c, err := range db.IterateCollections(ctx, id) {
+			if err != nil {
+				return nil, err
+			}
+			sc, err := fromCollection(c)
+			if err != nil {
+				return nil, err
+			}
+			sd.Collections = append(sd.Collections, sc)
+		}
+		s.Databases = append(s.Databases, sd)
+	}
+	return s, nil
+}
+
+func fromCollection(c *gowrite.Collection) (Collection, error) {
+	attrs, err := c.TypedAttributes()
+	if err != nil {
+		return Collection{}, fmt.Errorf("schema: collection %s: %w", c.ID, err)
+	}
+	sc := Collection{
+		ID:               c.ID,
+		Name:             c.Name,
+		Permissions:      c.Permissions,
+		DocumentSecurity: c.DocumentSecurity,
+		Enabled:          ptr(c.Enabled),
+	}
+	for _, a := range attrs {
+		sc.Attributes = append(sc.Attributes, FromTyped(a))
+	}
+	indexes, err := c.TypedIndexes()
+	if err != nil {
+		return Collection{}, fmt.Errorf("schema: collection %s: %w", c.ID, err)
+	}
+	for _, idx := range indexes {
+		sc.Indexes = append(sc.Indexes, Index{Key: idx.Key, Type: string(idx.Type), Attributes: idx.Attributes, Orders: idx.Orders})
+	}
+	return sc, nil
+}
+
+// Plan diffs the schema against the live project.
+func (s *Schema) Plan(ctx context.Context, db *gowrite.DatabaseService, opts Options) (*Plan, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Plan{}
+	for i := range s.Databases {
+		want := &s.Databases[i]
+		live, err := db.GetDatabaseCtx(ctx, want.ID)
+		if err != nil && !gowrite.IsNotFound(err) {
+			return nil, err
+		}
+		if live == nil {
+			p.add(Change{Action: ActionCreate, Kind: KindDatabase, DatabaseID: want.ID, Database: want, phase: phaseDatabase})
+			for j := range want.Collections {
+				if err := p.diffCollection(want.ID, &want.Collections[j], nil, opts); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if live.Name != want.Name || live.Enabled != enabled(want.Enabled) {
+			p.add(Change{Action: ActionUpdate, Kind: KindDatabase, DatabaseID: want.ID, Database: want, Reason: "name or enabled", phase: phaseDatabase})
+		}
+
+		byID := make(map[string]*gowrite.Collection)
+		for c, err := range db.IterateCollections(ctx, want.ID) {
+			if err != nil {
+				return nil, err
+			}
+			byID[c.ID] = c
+		}
+		for j := range want.Collections {
+			wc := &want.Collections[j]
+			lc := byID[wc.ID]
+			var current *Collection
+			if lc != nil {
+				c, err := fromCollection(lc)
+				if err != nil {
+					return nil, err
+				}
+				current = &c
+			}
+			if err := p.diffCollection(want.ID, wc, current, opts); err != nil {
+				return nil, err
+			}
+			delete(byID, wc.ID)
+		}
+		if opts.Prune {
+			for _, id := range slices.Sorted(maps.Keys(byID)) {
+				p.add(Change{Action: ActionDelete, Kind: KindCollection, DatabaseID: want.ID, CollectionID: id, phase: phaseDeleteCollection})
+			}
+		}
+	}
+
+	sort.SliceStable(p.Changes, func(i, j int) bool { return p.Changes[i].phase < p.Changes[j].phase })
+	return p, nil
+}
+
+func (p *Plan) add(c Change) {
+	p.Changes = append(p.Changes, c)
+}
+
+// diffCollection plans the changes of one collection; live is nil when it does not exist.
+func (p *Plan) diffCollection(databaseID string, want, live *Collection, opts Options) error {
+	base := Change{DatabaseID: databaseID, CollectionID: want.ID}
+	if live == nil {
+		p.add(with(base, Change{Action: ActionCreate, Kind: KindCollection, Collection: want, phase: phaseCollection}))
+		live = &Collection{}
+	} else if live.Name != want.Name || live.DocumentSecurity != want.DocumentSecurity ||
+		enabled(live.Enabled) != enabled(want.Enabled) || !samePermissions(live.Permissions, want.Permissions) {
+		p.add(with(base, Change{Action: ActionUpdate, Kind: KindCollection, Collection: want, Reason: "settings or permissions", phase: phaseCollection}))
+	}
+
+	// The child side of a relationship is created and removed together with its parent.
+	liveAttrs := make(map[string]Attribute, len(live.Attributes))
+	for _, a := range live.Attributes {
+		if a.Side != "child" {
+			liveAttrs[a.Key] = a
+		}
+	}
+	for i := range want.Attributes {
+		wa := &want.Attributes[i]
+		if wa.Side == "child" {
+			continue
+		}
+		phase := phaseAttribute
+		if wa.Type == string(gowrite.AttributeRelationship) {
+			phase = phaseRelationship
+		}
+		la, exists := liveAttrs[wa.Key]
+		delete(liveAttrs, wa.Key)
+		if !exists {
+			p.add(with(base, Change{Action: ActionCreate, Kind: KindAttribute, Key: wa.Key, Attribute: wa, phase: phase}))
+			continue
+		}
+
+		typed, err := wa.Typed()
+		if err != nil {
+			return err
+		}
+		normalized := FromTyped(typed)
+		if reason := recreateReason(la, normalized); reason != "" {
+			if !opts.Prune {
+				return fmt.Errorf("schema: %s/%s: attribute %q %s; set Prune to recreate it", databaseID, want.ID, wa.Key, reason)
+			}
+			p.add(with(base, Change{Action: ActionDelete, Kind: KindAttribute, Key: wa.Key, Reason: reason, phase: phaseDeleteAttribute}))
+			p.add(with(base, Change{Action: ActionCreate, Kind: KindAttribute, Key: wa.Key, Attribute: wa, Reason: reason, phase: phase}))
+			continue
+		}
+		if reason := updateReason(la, normalized); reason != "" {
+			p.add(with(base, Change{Action: ActionUpdate, Kind: KindAttribute, Key: wa.Key, Attribute: wa, Reason: reason, phase: phase}))
+		}
+	}
+	if opts.Prune {
+		for _, key := range slices.Sorted(maps.Keys(liveAttrs)) {
+			p.add(with(base, Change{Action: ActionDelete, Kind: KindAttribute, Key: key, phase: phaseDeleteAttribute}))
+		}
+	}
+
+	liveIndexes := make(map[string]Index, len(live.Indexes))
+	for _, idx := range live.Indexes {
+		liveIndexes[idx.Key] = idx
+	}
+	for i := range want.Indexes {
+		wi := &want.Indexes[i]
+		li, exists := liveIndexes[wi.Key]
+		delete(liveIndexes, wi.Key)
+		if exists && sameIndex(li, *wi) {
+			continue
+		}
+		if exists {
+			// Indexes cannot be updated, only replaced.
+			p.add(with(base, Change{Action: ActionDelete, Kind: KindIndex, Key: wi.Key, Reason: "definition changed", phase: phaseDeleteIndex}))
+		}
+		p.add(with(base, Change{Action: ActionCreate, Kind: KindIndex, Key: wi.Key, Index: wi, phase: phaseIndex}))
+	}
+	if opts.Prune {
+		for _, key := range slices.Sorted(maps.Keys(liveIndexes)) {
+			p.add(with(base, Change{Action: ActionDelete, Kind: KindIndex, Key: key, phase: phaseDeleteIndex}))
+		}
+	}
+	return nil
+}
+
+// with fills the location of c from base.
+func with(base, c Change) Change {
+	c.DatabaseID, c.CollectionID = base.DatabaseID, base.CollectionID
+	return c
+}
+
+// recreateReason describes differences that the server cannot update in place.
+func recreateReason(live, want Attribute) string {
+	switch {
+	case live.Type != want.Type:
+		return fmt.Sprintf("type changed from %s to %s", live.Type, want.Type)
+	case live.Array != want.Array:
+		return "array changed"
+	case live.RelatedCollection != want.RelatedCollection || live.RelationType != want.RelationType ||
+		live.TwoWay != want.TwoWay || (want.TwoWayKey != "" && live.TwoWayKey != want.TwoWayKey):
+		return "relationship changed"
+	}
+	return ""
+}
+
+// updateReason describes differences that can be updated in place.
+func updateReason(live, want Attribute) string {
+	var diffs []string
+	if live.Required != want.Required {
+		diffs = append(diffs, "required")
+	}
+	if !reflect.DeepEqual(live.Default, want.Default) {
+		diffs = append(diffs, "default")
+	}
+	if live.Size != want.Size {
+		diffs = append(diffs, "size")
+	}
+	if !reflect.DeepEqual(live.Min, want.Min) || !reflect.DeepEqual(live.Max, want.Max) {
+		diffs = append(diffs, "bounds")
+	}
+	if !slices.Equal(live.Elements, want.Elements) {
+		diffs = append(diffs, "elements")
+	}
+	if want.OnDelete != "" && live.OnDelete != want.OnDelete {
+		diffs = append(diffs, "onDelete")
+	}
+	return strings.Join(diffs, ", ")
+}
+
+func sameIndex(live, want Index) bool {
+	if live.Type != want.Type || !slices.Equal(live.Attributes, want.Attributes) {
+		return false
+	}
+	return want.Orders == nil || slices.Equal(live.Orders, want.Orders)
+}
+
+func samePermissions(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func enabled(b *bool) bool {
+	return b == nil || *b
+}
+
+// Apply runs the changes in order. Before indexes are created it waits until the
+// collections' attributes are available, and it waits for the indexes before
+// returning, so the project is usable once Apply succeeds. Bound the wait with a
+// context deadline.
+func (p *Plan) Apply(ctx context.Context, db *gowrite.DatabaseService) error {
+	touched := make(map[[2]string]bool)
+	waitTouched := func() error {
+		for c := range touched {
+			if _, err := db.WaitForCollectionReady(ctx, c[0], c[1]); err != nil {
+				return err
+			}
+		}
+		clear(touched)
+		return nil
+	}
+
+	for i, c := range p.Changes {
+		if c.phase == phaseIndex && (i == 0 || p.Changes[i-1].phase < phaseIndex) {
+			if err := waitTouched(); err != nil {
+				return err
+			}
+		}
+		if err := apply(ctx, db, c); err != nil {
+			return fmt.Errorf("schema: %s: %w", c, err)
+		}
+		if c.Kind == KindAttribute || c.Kind == KindIndex {
+			touched[[2]string{c.DatabaseID, c.CollectionID}] = true
+		}
+	}
+	return waitTouched()
+}
+
+func apply(ctx context.Context, db *gowrite.DatabaseService, c Change) error {
+	switch c.Kind {
+	case KindDatabase:
+		d := c.Database
+		if c.Action == ActionCreate {
+			_, err := db.CreateDatabaseCtx(ctx, d.ID, d.Name, enabled(d.Enabled))
+			return err
+		}
+		_, err := db.UpdateDatabaseCtx(ctx, d.ID, d.Name, enabled(d.Enabled))
+		return err
+
+	case KindCollection:
+		col := c.Collection
+		switch c.Action {
+		case ActionCreate:
+			_, err := db.CreateCollectionCtx(ctx, c.DatabaseID, col.ID, col.Name, col.Permissions, col.DocumentSecurity, enabled(col.Enabled))
+			return err
+		case ActionUpdate:
+			_, err := db.UpdateCollectionCtx(ctx, c.DatabaseID, col.ID, col.Name, col.Permissions, col.DocumentSecurity, enabled(col.Enabled))
+			return err
+		}
+		return db.DeleteCollectionCtx(ctx, c.DatabaseID, c.CollectionID)
+
+	case KindAttribute:
+		switch c.Action {
+		case ActionCreate:
+			typed, err := c.Attribute.Typed()
+			if err != nil {
+				return err
+			}
+			_, err = db.CreateTypedAttributeCtx(ctx, c.DatabaseID, c.CollectionID, typed)
+			return err
+		case ActionUpdate:
+			attrType, updates := updatePayload(*c.Attribute)
+			_, err := db.UpdateAttributeCtx(ctx, c.DatabaseID, c.CollectionID, c.Key, attrType, updates)
+			return err
+		}
+		if err := db.DeleteAttributeCtx(ctx, c.DatabaseID, c.CollectionID, c.Key); err != nil {
+			return err
+		}
+		// Deletion is asynchronous; a recreation with the same key has to wait for it.
+		return waitGone(ctx, func() error {
+			_, err := db.GetAttributeCtx(ctx, c.DatabaseID, c.CollectionID, c.Key)
+			return err
+		})
+
+	case KindIndex:
+		if c.Action == ActionCreate {
+			idx := c.Index
+			_, err := db.CreateIndexCtx(ctx, c.DatabaseID, c.CollectionID, idx.Key, gowrite.IndexType(idx.Type), idx.Attributes, idx.Orders, nil)
+			return err
+		}
+		if err := db.DeleteIndexCtx(ctx, c.DatabaseID, c.CollectionID, c.Key); err != nil {
+			return err
+		}
+		return waitGone(ctx, func() error {
+			_, err := db.GetIndexCtx(ctx, c.DatabaseID, c.CollectionID, c.Key)
+			return err
+		})
+	}
+	return fmt.Errorf("unknown change kind %q", c.Kind)
+}
+
+// updatePayload builds the body of an attribute update for the attribute's type.
+func updatePayload(a Attribute) (gowrite.AttributeType, map[string]interface{}) {
+	attrType := gowrite.AttributeType(a.Type)
+	if attrType == gowrite.AttributeRelationship {
+		return attrType, map[string]interface{}{"onDelete": a.OnDelete}
+	}
+	updates := map[string]interface{}{
+		"required": a.Required,
+		"default":  a.Default,
+	}
+	switch attrType {
+	case gowrite.AttributeString:
+		updates["size"] = a.Size
+	case gowrite.AttributeInteger:
+		// The server requires both bounds; a missing one is unbounded.
+		updates["min"], updates["max"] = int64(math.MinInt64), int64(math.MaxInt64)
+		if a.Min != nil {
+			updates["min"] = int64(*a.Min)
+		}
+		if a.Max != nil {
+			updates["max"] = int64(*a.Max)
+		}
+	case gowrite.AttributeFloat:
+		updates["min"], updates["max"] = -math.MaxFloat64, math.MaxFloat64
+		if a.Min != nil {
+			updates["min"] = *a.Min
+		}
+		if a.Max != nil {
+			updates["max"] = *a.Max
+		}
+	case gowrite.AttributeEnum:
+		updates["elements"] = a.Elements
+	}
+	return attrType, updates
+}
+
+// waitGone polls get until it reports that the object no longer exists.
+func waitGone(ctx context.Context, get func() error) error {
+	for delay := 100 * time.Millisecond; ; delay = min(delay*2, 2*time.Second) {
+		err := get()
+		if gowrite.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Package schema describes Appwrite databases declaratively and reconciles a live
+// project with the description.
+//
+// A schema is loaded from JSON or YAML, or built in Go:
+//
+//	databases:
+//	  - id: blog
+//	    name: Blog
+//	    collections:
+//	      - id: users
+//	        name: Users
+//	        attributes:
+//	          - {key: name, type: string, size: 128, required: true}
+//	      - id: posts
+//	        name: Posts
+//	        permissions: ['read("any")']
+//	        attributes:
+//	          - {key: title, type: string, size: 255, required: true}
+//	          - {key: views, type: integer, min: 0, default: 0}
+//	          - {key: author, type: relationship, relatedCollection: users, relationType: manyToOne}
+//	        indexes:
+//	          - {key: by_title, type: key, attributes: [title]}
+//
+// Plan diffs the schema against the project and Apply runs the resulting changes in
+// dependency order, waiting for the server to provision attributes and indexes.
+package schema
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/dm-vev/gowrite"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the desired state of a set of databases.
+type Schema struct {
+	Databases []Database `json:"databases" yaml:"databases"`
+}
+
+// Database describes a database and the collections it should contain.
+type Database struct {
+	ID          string       `json:"id" yaml:"id"`
+	Name        string       `json:"name" yaml:"name"`
+	Enabled     *bool        `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Collections []Collection `json:"collections" yaml:"collections"`
+}
+
+// Collection describes a collection with its attributes and indexes.
+type Collection struct {
+	ID               string      `json:"id" yaml:"id"`
+	Name             string      `json:"name" yaml:"name"`
+	Permissions      []string    `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	DocumentSecurity bool        `json:"documentSecurity,omitempty" yaml:"documentSecurity,omitempty"`
+	Enabled          *bool       `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Attributes       []Attribute `json:"attributes" yaml:"attributes"`
+	Indexes          []Index     `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+}
+
+// Attribute describes an attribute. Type is one of the gowrite.Attribute* types; the
+// remaining fields apply to the types that support them.
+type Attribute struct {
+	Key      string      `json:"key" yaml:"key"`
+	Type     string      `json:"type" yaml:"type"`
+	Required bool        `json:"required,omitempty" yaml:"required,omitempty"`
+	Array    bool        `json:"array,omitempty" yaml:"array,omitempty"`
+	Default  interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// Size is the maximum length of string attributes.
+	Size    int  `json:"size,omitempty" yaml:"size,omitempty"`
+	Encrypt bool `json:"encrypt,omitempty" yaml:"encrypt,omitempty"`
+	// Min and Max bound integer and float attributes; nil leaves that side unbounded.
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	// Elements are the allowed values of enum attributes.
+	Elements []string `json:"elements,omitempty" yaml:"elements,omitempty"`
+
+	RelatedCollection string `json:"relatedCollection,omitempty" yaml:"relatedCollection,omitempty"`
+	RelationType      string `json:"relationType,omitempty" yaml:"relationType,omitempty"`
+	TwoWay            bool   `json:"twoWay,omitempty" yaml:"twoWay,omitempty"`
+	TwoWayKey         string `json:"twoWayKey,omitempty" yaml:"twoWayKey,omitempty"`
+	OnDelete          string `json:"onDelete,omitempty" yaml:"onDelete,omitempty"`
+	// Side is "child" for the attribute the server adds to the related collection of
+	// a two-way relationship. Plan leaves child sides to their parent attribute.
+	Side string `json:"side,omitempty" yaml:"side,omitempty"`
+}
+
+// Index describes an index.
+type Index struct {
+	Key        string   `json:"key" yaml:"key"`
+	Type       string   `json:"type" yaml:"type"`
+	Attributes []string `json:"attributes" yaml:"attributes"`
+	Orders     []string `json:"orders,omitempty" yaml:"orders,omitempty"`
+}
+
+// Load reads a schema from a JSON or YAML file and validates it.
+func Load(path string) (*Schema, error) {
+	s, err := LoadUnvalidated(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadUnvalidated is like Load but does not validate the schema, for tools such as
+// codegen that accept attribute types Validate rejects.
+func LoadUnvalidated(path string) (*Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeUnvalidated(f)
+}
+
+// Decode reads a schema in JSON or YAML from r and validates it.
+func Decode(r io.Reader) (*Schema, error) {
+	s, err := DecodeUnvalidated(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DecodeUnvalidated is like Decode but does not validate the schema.
+func DecodeUnvalidated(r io.Reader) (*Schema, error) {
+	var s Schema
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	return &s, nil
+}
+
+// Validate checks that IDs are set and unique, that every attribute is valid for its
+// type and that indexes only reference declared attributes.
+func (s *Schema) Validate() error {
+	databases := make(map[string]bool)
+	for _, d := range s.Databases {
+		if d.ID == "" || databases[d.ID] {
+			return fmt.Errorf("schema: missing or duplicate database id %q", d.ID)
+		}
+		databases[d.ID] = true
+
+		collections := make(map[string]bool)
+		for _, c := range d.Collections {
+			if c.ID == "" || collections[c.ID] {
+				return fmt.Errorf("schema: database %s: missing or duplicate collection id %q", d.ID, c.ID)
+			}
+			collections[c.ID] = true
+		}
+
+		for _, c := range d.Collections {
+			keys := make(map[string]bool)
+			for _, a := range c.Attributes {
+				if keys[a.Key] {
+					return fmt.Errorf("schema: %s/%s: duplicate attribute %q", d.ID, c.ID, a.Key)
+				}
+				keys[a.Key] = true
+				typed, err := a.Typed()
+				if err == nil {
+					err = typed.Validate()
+				}
+				if err != nil {
+					return fmt.Errorf("schema: %s/%s: %w", d.ID, c.ID, err)
+				}
+				if a.Type == string(gowrite.AttributeRelationship) && !collections[a.RelatedCollection] {
+					return fmt.Errorf("schema: %s/%s: attribute %q relates to undeclared collection %q", d.ID, c.ID, a.Key, a.RelatedCollection)
+				}
+			}
+			indexes := make(map[string]bool)
+			for _, idx := range c.Indexes {
+				if idx.Key == "" || indexes[idx.Key] {
+					return fmt.Errorf("schema: %s/%s: missing or duplicate index key %q", d.ID, c.ID, idx.Key)
+				}
+				indexes[idx.Key] = true
+				for _, k := range idx.Attributes {
+					if !keys[k] && !strings.HasPrefix(k, "$") {
+						return fmt.Errorf("schema: %s/%s: index %q references undeclared attribute %q", d.ID, c.ID, idx.Key, k)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Typed converts the attribute into the gowrite model of its type.
+func (a Attribute) Typed() (gowrite.TypedAttribute, error) {
+	base := gowrite.Attribute{Key: a.Key, Type: a.Type, Required: a.Required, Array: a.Array}
+	var err error
+	switch gowrite.AttributeType(a.Type) {
+	case gowrite.AttributeString:
+		t := &gowrite.StringAttribute{Attribute: base, Size: a.Size, Encrypt: a.Encrypt}
+		t.Default, err = defaultAs[string](a)
+		return t, err
+	case gowrite.AttributeInteger:
+		t := &gowrite.IntegerAttribute{Attribute: base}
+		if t.Min, err = intBound(a.Key, "min", a.Min); err != nil {
+			return nil, err
+		}
+		if t.Max, err = intBound(a.Key, "max", a.Max); err != nil {
+			return nil, err
+		}
+		def, err := defaultAs[float64](a)
+		if err != nil {
+			return nil, err
+		}
+		t.Default, err = intBound(a.Key, "default", def)
+		return t, err
+	case gowrite.AttributeFloat:
+		t := &gowrite.FloatAttribute{Attribute: base, Min: a.Min, Max: a.Max}
+		t.Default, err = defaultAs[float64](a)
+		return t, err
+	case gowrite.AttributeBoolean:
+		t := &gowrite.BooleanAttribute{Attribute: base}
+		t.Default, err = defaultAs[bool](a)
+		return t, err
+	case gowrite.AttributeDatetime:
+		t := &gowrite.DatetimeAttribute{Attribute: base}
+		t.Default, err = defaultAs[string](a)
+		return t, err
+	case gowrite.AttributeEmail:
+		t := &gowrite.EmailAttribute{Attribute: base}
+		t.Default, err = defaultAs[string](a)
+		return t, err
+	case gowrite.AttributeIP:
+		t := &gowrite.IPAttribute{Attribute: base}
+		t.Default, err = defaultAs[string](a)
+		return t, err
+	case gowrite.AttributeURL:
+		t := &gowrite.URLAttribute{Attribute: base}
+		t.Default, err = defaultAs[string](a)
+		return t, err
+	case gowrite.AttributeEnum:
+		t := &gowrite.EnumAttribute{Attribute: base, Elements: a.Elements}
+		t.Default, err = defaultAs[string](a)
+		return t, err
+	case gowrite.AttributeRelationship:
+		return &gowrite.RelationshipAttribute{
+			Attribute:         base,
+			RelatedCollection: a.RelatedCollection,
+			Type:              gowrite.RelationType(a.RelationType),
+			TwoWay:            a.TwoWay,
+			TwoWayKey:         a.TwoWayKey,
+			OnDelete:          gowrite.OnDelete(a.OnDelete),
+			Side:              a.Side,
+		}, nil
+	}
+	return nil, fmt.Errorf("attribute %q: unknown type %q", a.Key, a.Type)
+}
+
+// FromTyped converts a gowrite attribute model into a schema attribute.
+func FromTyped(t gowrite.TypedAttribute) Attribute {
+	base := t.Base()
+	a := Attribute{Key: base.Key, Type: base.Type, Required: base.Required, Array: base.Array}
+	switch t := t.(type) {
+	case *gowrite.StringAttribute:
+		a.Size, a.Encrypt, a.Default = t.Size, t.Encrypt, deref(t.Default)
+	case *gowrite.IntegerAttribute:
+		a.Type = string(gowrite.AttributeInteger)
+		if t.Min != nil && *t.Min != math.MinInt64 {
+			a.Min = ptr(float64(*t.Min))
+		}
+		if t.Max != nil && *t.Max != math.MaxInt64 {
+			a.Max = ptr(float64(*t.Max))
+		}
+		if t.Default != nil {
+			a.Default = float64(*t.Default)
+		}
+	case *gowrite.FloatAttribute:
+		a.Type = string(gowrite.AttributeFloat)
+		if t.Min != nil && *t.Min != -math.MaxFloat64 {
+			a.Min = t.Min
+		}
+		if t.Max != nil && *t.Max != math.MaxFloat64 {
+			a.Max = t.Max
+		}
+		a.Default = deref(t.Default)
+	case *gowrite.BooleanAttribute:
+		a.Default = deref(t.Default)
+	case *gowrite.DatetimeAttribute:
+		a.Default = deref(t.Default)
+	case *gowrite.EmailAttribute:
+		a.Type, a.Default = string(gowrite.AttributeEmail), deref(t.Default)
+	case *gowrite.IPAttribute:
+		a.Type, a.Default = string(gowrite.AttributeIP), deref(t.Default)
+	case *gowrite.URLAttribute:
+		a.Type, a.Default = string(gowrite.AttributeURL), deref(t.Default)
+	case *gowrite.EnumAttribute:
+		a.Type, a.Elements, a.Default = string(gowrite.AttributeEnum), t.Elements, deref(t.Default)
+	case *gowrite.RelationshipAttribute:
+		a.RelatedCollection = t.RelatedCollection
+		a.RelationType = string(t.Type)
+		a.TwoWay = t.TwoWay
+		a.TwoWayKey = t.TwoWayKey
+		a.OnDelete = string(t.OnDelete)
+		if t.Side == "child" {
+			a.Side = t.Side
+		}
+	}
+	return a
+}
+
+// defaultAs converts the default value of a to T.
+func defaultAs[T any](a Attribute) (*T, error) {
+	if a.Default == nil {
+		return nil, nil
+	}
+	var v interface{} = a.Default
+	if n, ok := v.(int); ok {
+		v = float64(n)
+	}
+	t, ok := v.(T)
+	if !ok {
+		return nil, fmt.Errorf("attribute %q: default %v does not fit type %s", a.Key, a.Default, a.Type)
+	}
+	return &t, nil
+}
+
+// intBound converts a bound or default of an integer attribute, rejecting fractions.
+func intBound(key, name string, f *float64) (*int64, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if *f != math.Trunc(*f) {
+		return nil, fmt.Errorf("attribute %q: %s %v is not an integer", key, name, *f)
+	}
+	return ptr(int64(*f)), nil
+}
+
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
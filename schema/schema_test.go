@@ -0,0 +1,248 @@
+package schema_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+	"github.com/dm-vev/gowrite/schema"
+)
+
+const blogSchema = `
+databases:
+  - id: blog
+    name: Blog
+    collections:
+      - id: posts
+        name: Posts
+        attributes:
+          - {key: title, type: string, size: 255, required: true}
+          - {key: views, type: integer, min: 0, default: 0}
+        indexes:
+          - {key: by_title, type: key, attributes: [title]}
+`
+
+// liveProject serves a blog database whose posts collection has a shorter title, a
+// legacy attribute and no indexes, and records the write requests it receives.
+func liveProject(t *testing.T) (*gowrite.DatabaseService, *[]string) {
+	var (
+		mu     sync.Mutex
+		writes []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			mu.Lock()
+			writes = append(writes, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/v1/databases/blog/collections/posts"))
+			mu.Unlock()
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/databases/blog":
+			fmt.Fprint(w, `{"$id":"blog","name":"Blog","enabled":true}`)
+		case "/v1/databases/blog/collections":
+			fmt.Fprint(w, `{"total":1,"collections":[{"$id":"posts","name":"Posts","enabled":true,"permissions":[],`+
+				`"attributes":[{"key":"title","type":"string","status":"available","required":true,"size":100},`+
+				`{"key":"legacy","type":"boolean","status":"available"}],"indexes":[]}]}`)
+		case "/v1/databases/blog/collections/posts":
+			fmt.Fprint(w, `{"$id":"posts","attributes":[{"key":"title","type":"string","status":"available"}],"indexes":[{"key":"by_title","status":"available"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found","code":404,"type":"attribute_not_found"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")), &writes
+}
+
+func TestPlanAndApply(t *testing.T) {
+	s, err := schema.Decode(strings.NewReader(blogSchema))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	db, writes := liveProject(t)
+	ctx := context.Background()
+
+	plan, err := s.Plan(ctx, db, schema.Options{})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	want := "update attribute blog/posts.title (size)\n" +
+		"create attribute blog/posts.views\n" +
+		"create index blog/posts.by_title\n"
+	if plan.String() != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", plan, want)
+	}
+
+	plan, err = s.Plan(ctx, db, schema.Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Plan with Prune: %v", err)
+	}
+	if first := plan.Changes[0].String(); first != "delete attribute blog/posts.legacy" {
+		t.Fatalf("first change = %s", first)
+	}
+
+	if err := plan.Apply(ctx, db); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	wantWrites := []string{
+		"DELETE /attributes/legacy",
+		"PATCH /attributes/string/title",
+		"POST /attributes/integer",
+		"POST /indexes",
+	}
+	if fmt.Sprint(*writes) != fmt.Sprint(wantWrites) {
+		t.Fatalf("writes = %v, want %v", *writes, wantWrites)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := schema.Decode(strings.NewReader(`
+databases:
+  - id: blog
+    collections:
+      - id: posts
+        attributes:
+          - {key: title, type: string, size: 10}
+        indexes:
+          - {key: by_body, type: key, attributes: [body]}
+`))
+	if err == nil || !strings.Contains(err.Error(), `undeclared attribute "body"`) {
+		t.Fatalf("err = %v", err)
+	}
+
+	_, err = schema.Decode(strings.NewReader(`
+databases:
+  - id: blog
+    collections:
+      - id: posts
+        attributes:
+          - {key: views, type: integer, min: 1.5}
+`))
+	if err == nil || !strings.Contains(err.Error(), "min 1.5 is not an integer") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestApplySendsBothBounds(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PATCH":
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+			fmt.Fprint(w, `{}`)
+		case r.URL.Path == "/v1/databases/blog":
+			fmt.Fprint(w, `{"$id":"blog","name":"Blog","enabled":true}`)
+		case r.URL.Path == "/v1/databases/blog/collections":
+			fmt.Fprint(w, `{"total":1,"collections":[{"$id":"posts","name":"Posts","enabled":true,"permissions":[],`+
+				`"attributes":[{"key":"views","type":"integer","status":"available","min":5,"max":100}],"indexes":[]}]}`)
+		default:
+			fmt.Fprint(w, `{"$id":"posts","attributes":[{"key":"views","type":"integer","status":"available"}],"indexes":[]}`)
+		}
+	}))
+	defer srv.Close()
+
+	// Dropping min clears it, keeping max requires sending it again.
+	s, err := schema.Decode(strings.NewReader(`
+databases:
+  - id: blog
+    name: Blog
+    collections:
+      - id: posts
+        name: Posts
+        attributes:
+          - {key: views, type: integer, max: 100, required: true}
+`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	ctx := context.Background()
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	plan, err := s.Plan(ctx, db, schema.Options{})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if err := plan.Apply(ctx, db); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if !strings.Contains(body, `"min":-9223372036854775808`) || !strings.Contains(body, `"max":100`) {
+		t.Fatalf("update body = %s", body)
+	}
+}
+
+func TestPlanPrunesEveryPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/databases/blog":
+			fmt.Fprint(w, `{"$id":"blog","name":"Blog","enabled":true}`)
+		case "/v1/databases/blog/collections":
+			// A full first page of declared collections, then one undeclared collection.
+			if strings.Contains(r.URL.RawQuery, "cursorAfter") {
+				fmt.Fprint(w, `{"total":101,"collections":[{"$id":"stale","enabled":true,"attributes":[],"indexes":[]}]}`)
+				return
+			}
+			cols := make([]string, 100)
+			for i := range cols {
+				cols[i] = fmt.Sprintf(`{"$id":"c%03d","name":"C","enabled":true,"permissions":[],"attributes":[],"indexes":[]}`, i)
+			}
+			fmt.Fprintf(w, `{"total":101,"collections":[%s]}`, strings.Join(cols, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found","code":404,"type":"collection_not_found"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+
+	want := schema.Database{ID: "blog", Name: "Blog"}
+	for i := 0; i < 100; i++ {
+		want.Collections = append(want.Collections, schema.Collection{ID: fmt.Sprintf("c%03d", i), Name: "C"})
+	}
+	s := &schema.Schema{Databases: []schema.Database{want}}
+	plan, err := s.Plan(context.Background(), db, schema.Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.String() != "delete collection blog/stale\n" {
+		t.Fatalf("plan:\n%s", plan)
+	}
+}
+
+func ExampleDecode() {
+	s, err := schema.Decode(strings.NewReader(`
+databases:
+  - id: blog
+    name: Blog
+    collections:
+      - id: users
+        name: Users
+        attributes:
+          - {key: name, type: string, size: 128, required: true}
+      - id: posts
+        name: Posts
+        permissions: ['read("any")']
+        attributes:
+          - {key: title, type: string, size: 255, required: true}
+          - {key: views, type: integer, min: 0, default: 0}
+          - {key: author, type: relationship, relatedCollection: users, relationType: manyToOne}
+        indexes:
+          - {key: by_title, type: key, attributes: [title]}
+`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, c := range s.Databases[0].Collections {
+		fmt.Println(c.ID, len(c.Attributes), len(c.Indexes))
+	}
+	// Output:
+	// users 1 0
+	// posts 3 1
+}
@@ -1,14 +1,17 @@
 package gowrite
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"net/http"
+	"iter"
+	"net/url"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/dm-vev/gowrite/query"
 )
 
 type StorageService struct {
@@ -17,22 +20,26 @@ type StorageService struct {
 
 // Bucket представляет хранилище в Appwrite.
 type Bucket struct {
-	ID                    string   `json:"$id"`
-	Name                  string   `json:"name"`
-	Permissions           []string `json:"$permissions"`
-	FileSecurity          bool     `json:"fileSecurity"`
-	Enabled               bool     `json:"enabled"`
-	MaximumFileSize       int64    `json:"maximumFileSize"`
-	AllowedFileExtensions []string `json:"allowedFileExtensions"`
-	Compression           string   `json:"compression"`
-	Encryption            bool     `json:"encryption"`
-	Antivirus             bool     `json:"antivirus"`
+	ID                    string    `json:"$id"`
+	CreatedAt             time.Time `json:"$createdAt"`
+	UpdatedAt             time.Time `json:"$updatedAt"`
+	Name                  string    `json:"name"`
+	Permissions           []string  `json:"$permissions"`
+	FileSecurity          bool      `json:"fileSecurity"`
+	Enabled               bool      `json:"enabled"`
+	MaximumFileSize       int64     `json:"maximumFileSize"`
+	AllowedFileExtensions []string  `json:"allowedFileExtensions"`
+	Compression           string    `json:"compression"`
+	Encryption            bool      `json:"encryption"`
+	Antivirus             bool      `json:"antivirus"`
 }
 
 // File представляет файл в Appwrite.
 type File struct {
 	ID             string                 `json:"$id"`
 	BucketID       string                 `json:"bucketId"`
+	CreatedAt      time.Time              `json:"$createdAt"`
+	UpdatedAt      time.Time              `json:"$updatedAt"`
 	Name           string                 `json:"name"`
 	Signature      string                 `json:"signature"`
 	MimeType       string                 `json:"mimeType"`
@@ -47,11 +54,36 @@ func NewStorage(client *AppwriteClient) *StorageService {
 	return &StorageService{client}
 }
 
+// UnmarshalJSON для Bucket разбирает временные метки Appwrite.
+func (bk *Bucket) UnmarshalJSON(b []byte) error {
+	type Alias Bucket
+	aux := &struct {
+		*Alias
+		CreatedAt string `json:"$createdAt"`
+		UpdatedAt string `json:"$updatedAt"`
+	}{
+		Alias: (*Alias)(bk),
+	}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if bk.CreatedAt, err = parseTimestamp(aux.CreatedAt); err != nil {
+		return err
+	}
+	bk.UpdatedAt, err = parseTimestamp(aux.UpdatedAt)
+	return err
+}
+
 // Custom UnmarshalJSON для File
 func (f *File) UnmarshalJSON(b []byte) error {
 	type Alias File
 	aux := &struct {
 		*Alias
+		CreatedAt string `json:"$createdAt"`
+		UpdatedAt string `json:"$updatedAt"`
 	}{
 		Alias: (*Alias)(f),
 	}
@@ -60,12 +92,21 @@ func (f *File) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	var err error
+	if f.CreatedAt, err = parseTimestamp(aux.CreatedAt); err != nil {
+		return err
+	}
+	if f.UpdatedAt, err = parseTimestamp(aux.UpdatedAt); err != nil {
+		return err
+	}
+
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
 
-	// Удаляем известные поля
+	// Удаляем известные поля; $createdAt и $updatedAt остаются в Data для кода,
+	// который читал их оттуда до появления отдельных полей
 	delete(raw, "$id")
 	delete(raw, "bucketId")
 	delete(raw, "name")
@@ -82,7 +123,13 @@ func (f *File) UnmarshalJSON(b []byte) error {
 
 // ListBuckets получает список всех бакетов.
 func (s *StorageService) ListBuckets() ([]*Bucket, error) {
-	respBody, err := s.Client.sendRequest("GET", "/storage/buckets", nil)
+	return s.ListBucketsCtx(context.Background())
+}
+
+// ListBucketsCtx работает как ListBuckets, но использует ctx для запроса.
+func (s *StorageService) ListBucketsCtx(ctx context.Context) ([]*Bucket, error) {
+	op := newOperation("storage", "listBuckets")
+	respBody, err := s.Client.sendRequest(ctx, op, "GET", "/storage/buckets", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +148,11 @@ func (s *StorageService) ListBuckets() ([]*Bucket, error) {
 
 // CreateBucket создает новый бакет.
 func (s *StorageService) CreateBucket(bucketID, name string, permissions []string, fileSecurity, enabled bool, maximumFileSize int64, allowedFileExtensions []string, compression string, encryption, antivirus bool) (*Bucket, error) {
+	return s.CreateBucketCtx(context.Background(), bucketID, name, permissions, fileSecurity, enabled, maximumFileSize, allowedFileExtensions, compression, encryption, antivirus)
+}
+
+// CreateBucketCtx работает как CreateBucket, но использует ctx для запроса.
+func (s *StorageService) CreateBucketCtx(ctx context.Context, bucketID, name string, permissions []string, fileSecurity, enabled bool, maximumFileSize int64, allowedFileExtensions []string, compression string, encryption, antivirus bool) (*Bucket, error) {
 	payload := map[string]interface{}{
 		"bucketId":              bucketID,
 		"name":                  name,
@@ -114,7 +166,8 @@ func (s *StorageService) CreateBucket(bucketID, name string, permissions []strin
 		"antivirus":             antivirus,
 	}
 
-	respBody, err := s.Client.sendRequest("POST", "/storage/buckets", payload)
+	op := newOperation("storage", "createBucket", "bucketId", bucketID)
+	respBody, err := s.Client.sendRequest(ctx, op, "POST", "/storage/buckets", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -130,8 +183,14 @@ func (s *StorageService) CreateBucket(bucketID, name string, permissions []strin
 
 // GetBucket получает бакет по его ID.
 func (s *StorageService) GetBucket(bucketID string) (*Bucket, error) {
+	return s.GetBucketCtx(context.Background(), bucketID)
+}
+
+// GetBucketCtx работает как GetBucket, но использует ctx для запроса.
+func (s *StorageService) GetBucketCtx(ctx context.Context, bucketID string) (*Bucket, error) {
 	path := fmt.Sprintf("/storage/buckets/%s", bucketID)
-	respBody, err := s.Client.sendRequest("GET", path, nil)
+	op := newOperation("storage", "getBucket", "bucketId", bucketID)
+	respBody, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +206,11 @@ func (s *StorageService) GetBucket(bucketID string) (*Bucket, error) {
 
 // UpdateBucket обновляет бакет.
 func (s *StorageService) UpdateBucket(bucketID, name string, permissions []string, fileSecurity, enabled bool, maximumFileSize int64, allowedFileExtensions []string, compression string, encryption, antivirus bool) (*Bucket, error) {
+	return s.UpdateBucketCtx(context.Background(), bucketID, name, permissions, fileSecurity, enabled, maximumFileSize, allowedFileExtensions, compression, encryption, antivirus)
+}
+
+// UpdateBucketCtx работает как UpdateBucket, но использует ctx для запроса.
+func (s *StorageService) UpdateBucketCtx(ctx context.Context, bucketID, name string, permissions []string, fileSecurity, enabled bool, maximumFileSize int64, allowedFileExtensions []string, compression string, encryption, antivirus bool) (*Bucket, error) {
 	payload := map[string]interface{}{
 		"name":                  name,
 		"permissions":           permissions,
@@ -160,7 +224,8 @@ func (s *StorageService) UpdateBucket(bucketID, name string, permissions []strin
 	}
 
 	path := fmt.Sprintf("/storage/buckets/%s", bucketID)
-	respBody, err := s.Client.sendRequest("PUT", path, payload)
+	op := newOperation("storage", "updateBucket", "bucketId", bucketID)
+	respBody, err := s.Client.sendRequest(ctx, op, "PUT", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -176,15 +241,27 @@ func (s *StorageService) UpdateBucket(bucketID, name string, permissions []strin
 
 // DeleteBucket удаляет бакет.
 func (s *StorageService) DeleteBucket(bucketID string) error {
+	return s.DeleteBucketCtx(context.Background(), bucketID)
+}
+
+// DeleteBucketCtx работает как DeleteBucket, но использует ctx для запроса.
+func (s *StorageService) DeleteBucketCtx(ctx context.Context, bucketID string) error {
 	path := fmt.Sprintf("/storage/buckets/%s", bucketID)
-	_, err := s.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("storage", "deleteBucket", "bucketId", bucketID)
+	_, err := s.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	return err
 }
 
 // ListFiles получает список файлов в бакете.
 func (s *StorageService) ListFiles(bucketID string) ([]*File, error) {
+	return s.ListFilesCtx(context.Background(), bucketID)
+}
+
+// ListFilesCtx работает как ListFiles, но использует ctx для запроса.
+func (s *StorageService) ListFilesCtx(ctx context.Context, bucketID string) ([]*File, error) {
 	path := fmt.Sprintf("/storage/buckets/%s/files", bucketID)
-	respBody, err := s.Client.sendRequest("GET", path, nil)
+	op := newOperation("storage", "listFiles", "bucketId", bucketID)
+	respBody, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -201,76 +278,119 @@ func (s *StorageService) ListFiles(bucketID string) ([]*File, error) {
 	return result.Files, nil
 }
 
+// filesPageSize — размер страницы при обходе файлов бакета.
+const filesPageSize = 100
+
+// IterateFiles обходит все файлы бакета, в отличие от ListFiles, который возвращает
+// только первую страницу. Страницы запрашиваются по курсору по мере чтения.
+func (s *StorageService) IterateFiles(ctx context.Context, bucketID string) iter.Seq2[*File, error] {
+	return func(yield func(*File, error) bool) {
+		cursor := ""
+		for {
+			q := url.Values{}
+			q.Add("queries[]", query.Limit(filesPageSize))
+			if cursor != "" {
+				q.Add("queries[]", query.CursorAfter(cursor))
+			}
+			path := fmt.Sprintf("/storage/buckets/%s/files?%s", bucketID, q.Encode())
+			op := newOperation("storage", "listFiles", "bucketId", bucketID, "cursor", cursor)
+			respBody, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var result struct {
+				Files []*File `json:"files"`
+			}
+			if err := json.Unmarshal(respBody, &result); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, f := range result.Files {
+				if !yield(f, nil) {
+					return
+				}
+			}
+			if len(result.Files) < filesPageSize {
+				return
+			}
+			cursor = result.Files[len(result.Files)-1].ID
+		}
+	}
+}
+
 // CreateFile загружает новый файл в бакет.
 func (s *StorageService) CreateFile(bucketID, fileID, filePath string, permissions []string) (*File, error) {
-	path := fmt.Sprintf("/storage/buckets/%s/files", bucketID)
+	return s.CreateFileCtx(context.Background(), bucketID, fileID, filePath, permissions)
+}
 
+// CreateFileCtx работает как CreateFile, но использует ctx для запроса.
+func (s *StorageService) CreateFileCtx(ctx context.Context, bucketID, fileID, filePath string, permissions []string) (*File, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	return s.CreateFileFromReaderCtx(ctx, bucketID, fileID, filepath.Base(filePath), file, permissions)
+}
 
-	// Добавляем fileId
-	if err := writer.WriteField("fileId", fileID); err != nil {
-		return nil, err
-	}
+// CreateFileFromReader загружает в бакет файл с именем name, читая содержимое из r.
+// Длина берётся у io.Seeker или у типов с методом Len, как *bytes.Reader; содержимое
+// других потоков сначала сохраняется во временный файл. Если длина известна заранее,
+// используйте CreateFileFromStream.
+func (s *StorageService) CreateFileFromReader(bucketID, fileID, name string, r io.Reader, permissions []string) (*File, error) {
+	return s.CreateFileFromReaderCtx(context.Background(), bucketID, fileID, name, r, permissions)
+}
 
-	// Добавляем permissions
-	for _, permission := range permissions {
-		if err := writer.WriteField("permissions[]", permission); err != nil {
+// CreateFileFromReaderCtx работает как CreateFileFromReader, но использует ctx для запросов.
+func (s *StorageService) CreateFileFromReaderCtx(ctx context.Context, bucketID, fileID, name string, r io.Reader, permissions []string) (*File, error) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return s.CreateFileFromStreamCtx(ctx, bucketID, fileID, name, r, int64(v.Len()), permissions)
+	case io.Seeker:
+		pos, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
 			return nil, err
 		}
+		if _, err := v.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return s.CreateFileFromStreamCtx(ctx, bucketID, fileID, name, r, end-pos, permissions)
 	}
 
-	// Добавляем файл
-	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	tmp, err := os.CreateTemp("", "gowrite-upload-*")
 	if err != nil {
 		return nil, err
 	}
-	if _, err := io.Copy(part, file); err != nil {
-		return nil, err
-	}
-
-	writer.Close()
-
-	req, err := http.NewRequest("POST", s.Client.Endpoint+"/v1"+path, body)
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, r)
 	if err != nil {
 		return nil, err
 	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("X-Appwrite-Project", s.Client.ProjectID)
-	req.Header.Set("X-Appwrite-Key", s.Client.APIKey)
-	req.Header.Set("X-Appwrite-Response-Format", "1.6.0")
-
-	resp, err := s.Client.HTTPClient.Do(req)
-	if err != nil {
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var fileResp File
-	err = json.Unmarshal(respBody, &fileResp)
-	if err != nil {
-		return nil, err
-	}
-
-	return &fileResp, nil
+	return s.CreateFileFromStreamCtx(ctx, bucketID, fileID, name, tmp, size, permissions)
 }
 
 // GetFile получает файл по его ID.
 func (s *StorageService) GetFile(bucketID, fileID string) (*File, error) {
+	return s.GetFileCtx(context.Background(), bucketID, fileID)
+}
+
+// GetFileCtx работает как GetFile, но использует ctx для запроса.
+func (s *StorageService) GetFileCtx(ctx context.Context, bucketID, fileID string) (*File, error) {
 	path := fmt.Sprintf("/storage/buckets/%s/files/%s", bucketID, fileID)
-	respBody, err := s.Client.sendRequest("GET", path, nil)
+	op := newOperation("storage", "getFile", "bucketId", bucketID, "fileId", fileID)
+	respBody, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -286,13 +406,19 @@ func (s *StorageService) GetFile(bucketID, fileID string) (*File, error) {
 
 // UpdateFile обновляет файл.
 func (s *StorageService) UpdateFile(bucketID, fileID, name string, permissions []string) (*File, error) {
+	return s.UpdateFileCtx(context.Background(), bucketID, fileID, name, permissions)
+}
+
+// UpdateFileCtx работает как UpdateFile, но использует ctx для запроса.
+func (s *StorageService) UpdateFileCtx(ctx context.Context, bucketID, fileID, name string, permissions []string) (*File, error) {
 	payload := map[string]interface{}{
 		"name":        name,
 		"permissions": permissions,
 	}
 
 	path := fmt.Sprintf("/storage/buckets/%s/files/%s", bucketID, fileID)
-	respBody, err := s.Client.sendRequest("PUT", path, payload)
+	op := newOperation("storage", "updateFile", "bucketId", bucketID, "fileId", fileID)
+	respBody, err := s.Client.sendRequest(ctx, op, "PUT", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -308,45 +434,42 @@ func (s *StorageService) UpdateFile(bucketID, fileID, name string, permissions [
 
 // DeleteFile удаляет файл.
 func (s *StorageService) DeleteFile(bucketID, fileID string) error {
+	return s.DeleteFileCtx(context.Background(), bucketID, fileID)
+}
+
+// DeleteFileCtx работает как DeleteFile, но использует ctx для запроса.
+func (s *StorageService) DeleteFileCtx(ctx context.Context, bucketID, fileID string) error {
 	path := fmt.Sprintf("/storage/buckets/%s/files/%s", bucketID, fileID)
-	_, err := s.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("storage", "deleteFile", "bucketId", bucketID, "fileId", fileID)
+	_, err := s.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	return err
 }
 
 // DownloadFile скачивает файл.
 func (s *StorageService) DownloadFile(bucketID, fileID string) ([]byte, error) {
-	path := fmt.Sprintf("/storage/buckets/%s/files/%s/download", bucketID, fileID)
-
-	req, err := http.NewRequest("GET", s.Client.Endpoint+"/v1"+path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("X-Appwrite-Project", s.Client.ProjectID)
-	req.Header.Set("X-Appwrite-Key", s.Client.APIKey)
-	req.Header.Set("X-Appwrite-Response-Format", "1.6.0")
-
-	resp, err := s.Client.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return s.DownloadFileCtx(context.Background(), bucketID, fileID)
+}
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
-	}
+// DownloadFileCtx работает как DownloadFile, но использует ctx для запроса.
+func (s *StorageService) DownloadFileCtx(ctx context.Context, bucketID, fileID string) ([]byte, error) {
+	path := fmt.Sprintf("/storage/buckets/%s/files/%s/download", bucketID, fileID)
 
-	content, err := io.ReadAll(resp.Body)
+	op := newOperation("storage", "getFileDownload", "bucketId", bucketID, "fileId", fileID)
+	req, err := s.Client.newRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return content, nil
+	return s.Client.do(op, req)
 }
 
 // GetFilePreview получает превью файла.
 func (s *StorageService) GetFilePreview(bucketID, fileID string, params map[string]string) ([]byte, error) {
+	return s.GetFilePreviewCtx(context.Background(), bucketID, fileID, params)
+}
+
+// GetFilePreviewCtx работает как GetFilePreview, но использует ctx для запроса.
+func (s *StorageService) GetFilePreviewCtx(ctx context.Context, bucketID, fileID string, params map[string]string) ([]byte, error) {
 	path := fmt.Sprintf("/storage/buckets/%s/files/%s/preview", bucketID, fileID)
 
 	// Добавляем параметры
@@ -358,68 +481,35 @@ func (s *StorageService) GetFilePreview(bucketID, fileID string, params map[stri
 		path += q[:len(q)-1]
 	}
 
-	req, err := http.NewRequest("GET", s.Client.Endpoint+"/v1"+path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("X-Appwrite-Project", s.Client.ProjectID)
-	req.Header.Set("X-Appwrite-Key", s.Client.APIKey)
-	req.Header.Set("X-Appwrite-Response-Format", "1.6.0")
-
-	resp, err := s.Client.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	content, err := io.ReadAll(resp.Body)
+	op := newOperation("storage", "getFilePreview", "bucketId", bucketID, "fileId", fileID)
+	req, err := s.Client.newRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return content, nil
+	return s.Client.do(op, req)
 }
 
 // ViewFile получает содержимое файла для просмотра.
 func (s *StorageService) ViewFile(bucketID, fileID string) ([]byte, error) {
-	path := fmt.Sprintf("/storage/buckets/%s/files/%s/view", bucketID, fileID)
-
-	req, err := http.NewRequest("GET", s.Client.Endpoint+"/v1"+path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("X-Appwrite-Project", s.Client.ProjectID)
-	req.Header.Set("X-Appwrite-Key", s.Client.APIKey)
-	req.Header.Set("X-Appwrite-Response-Format", "1.6.0")
-
-	resp, err := s.Client.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return s.ViewFileCtx(context.Background(), bucketID, fileID)
+}
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
-	}
+// ViewFileCtx работает как ViewFile, но использует ctx для запроса.
+func (s *StorageService) ViewFileCtx(ctx context.Context, bucketID, fileID string) ([]byte, error) {
+	path := fmt.Sprintf("/storage/buckets/%s/files/%s/view", bucketID, fileID)
 
-	content, err := io.ReadAll(resp.Body)
+	op := newOperation("storage", "getFileView", "bucketId", bucketID, "fileId", fileID)
+	req, err := s.Client.newRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return content, nil
+	return s.Client.do(op, req)
 }
 
 // GetFileDownloadURL формирует URL для скачивания файла.
 func (s *StorageService) GetFileDownloadURL(bucketID, fileID string) string {
 	return fmt.Sprintf("%s/v1/storage/buckets/%s/files/%s/download?project=%s",
-		s.Client.Endpoint, bucketID, fileID, s.Client.ProjectID)
+		s.Client.baseURL(), bucketID, fileID, s.Client.ProjectID)
 }
@@ -0,0 +1,49 @@
+package gowrite
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// timestampLayout is the format Appwrite uses for $createdAt, $updatedAt and other
+// datetime fields, e.g. 2020-10-15T06:38:00.000+00:00.
+const timestampLayout = "2006-01-02T15:04:05.000-07:00"
+
+// parseTimestamp parses an Appwrite datetime. Empty strings, used for events that did
+// not happen yet, yield the zero time.
+func parseTimestamp(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// preciseTimestampLayout keeps the digits of servers that store sub-millisecond times.
+const preciseTimestampLayout = "2006-01-02T15:04:05.999999999-07:00"
+
+// formatTimestamp formats t in Appwrite's layout. Times with more than millisecond
+// precision keep every digit, so a value read from the server formats back to an
+// equal value, as the $updatedAt comparison of UpdateDocumentIfUnchanged requires.
+func formatTimestamp(t time.Time) string {
+	if t.Nanosecond()%int(time.Millisecond) != 0 {
+		return t.Format(preciseTimestampLayout)
+	}
+	return t.Format(timestampLayout)
+}
+
+// parseSequence accepts $sequence both as a JSON number and as a string, as servers
+// differ in how they encode it.
+func parseSequence(v interface{}) int64 {
+	switch s := v.(type) {
+	case float64:
+		return int64(s)
+	case json.Number:
+		n, _ := s.Int64()
+		return n
+	case string:
+		n, _ := strconv.ParseInt(s, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,244 @@
+package gowrite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// transactionMinVersion is the first server version with database transactions.
+const transactionMinVersion = "1.8.0"
+
+// ErrTransactionDone is returned when a committed or rolled back transaction is used.
+var ErrTransactionDone = errors.New("gowrite: transaction already committed or rolled back")
+
+// Transaction groups document writes that the server applies atomically on Commit.
+// Writes are staged locally and sent together when committing, so nothing is visible
+// to other readers before the commit. Transactions require Appwrite 1.8 or later.
+type Transaction struct {
+	ID        string
+	ExpiresAt time.Time
+
+	db   *DatabaseService
+	mu   sync.Mutex
+	ops  []transactionOperation
+	done bool
+}
+
+type transactionOperation struct {
+	Action       string                 `json:"action"`
+	DatabaseID   string                 `json:"databaseId"`
+	CollectionID string                 `json:"collectionId"`
+	DocumentID   string                 `json:"documentId,omitempty"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+}
+
+// Begin starts a transaction that the server discards after ttl unless it is
+// committed; a zero ttl uses the server default of five minutes.
+func (db *DatabaseService) Begin(ttl time.Duration) (*Transaction, error) {
+	return db.BeginCtx(context.Background(), ttl)
+}
+
+// BeginCtx is like Begin but uses ctx for the request.
+func (db *DatabaseService) BeginCtx(ctx context.Context, ttl time.Duration) (*Transaction, error) {
+	if !db.Client.serverAtLeast(ctx, transactionMinVersion) {
+		return nil, fmt.Errorf("gowrite: transactions require appwrite %s: %w", transactionMinVersion, errors.ErrUnsupported)
+	}
+
+	payload := map[string]interface{}{}
+	if ttl > 0 {
+		payload["ttl"] = int(ttl / time.Second)
+	}
+
+	op := newOperation("databases", "createTransaction")
+	respBody, err := db.Client.sendRequest(ctx, op, "POST", "/databases/transactions", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var aux struct {
+		ID        string `json:"$id"`
+		ExpiresAt string `json:"expiresAt"`
+	}
+	if err = json.Unmarshal(respBody, &aux); err != nil {
+		return nil, err
+	}
+	tx := &Transaction{ID: aux.ID, db: db}
+	if tx.ExpiresAt, err = parseTimestamp(aux.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// WithTransaction runs fn in a transaction and commits it when fn returns nil. When
+// fn fails the transaction is rolled back and fn's error returned; when fn panics it
+// is rolled back before the panic continues. A failed commit is rolled back by
+// Commit. Cached documents are only invalidated after a successful commit.
+func (db *DatabaseService) WithTransaction(ctx context.Context, fn func(tx *Transaction) error) error {
+	tx, err := db.BeginCtx(ctx, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			// Do not leave the transaction open on the server until it expires.
+			tx.RollbackCtx(ctx)
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rbErr := tx.RollbackCtx(ctx); rbErr != nil && !errors.Is(rbErr, ErrTransactionDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.CommitCtx(ctx)
+}
+
+func (tx *Transaction) stage(op transactionOperation) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTransactionDone
+	}
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// CreateDocument stages the creation of a document.
+func (tx *Transaction) CreateDocument(databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) error {
+	return tx.stage(transactionOperation{
+		Action: "create", DatabaseID: databaseID, CollectionID: collectionID, DocumentID: documentID,
+		Data: withPermissions(data, permissions),
+	})
+}
+
+// UpdateDocument stages an update of a document.
+func (tx *Transaction) UpdateDocument(databaseID, collectionID, documentID string, data map[string]interface{}, permissions []string) error {
+	return tx.stage(transactionOperation{
+		Action: "update", DatabaseID: databaseID, CollectionID: collectionID, DocumentID: documentID,
+		Data: withPermissions(data, permissions),
+	})
+}
+
+// DeleteDocument stages the deletion of a document.
+func (tx *Transaction) DeleteDocument(databaseID, collectionID, documentID string) error {
+	return tx.stage(transactionOperation{
+		Action: "delete", DatabaseID: databaseID, CollectionID: collectionID, DocumentID: documentID,
+	})
+}
+
+// IncrementDocumentAttribute stages an atomic increment of a numeric attribute, bounded
+// by max when it is not nil.
+func (tx *Transaction) IncrementDocumentAttribute(databaseID, collectionID, documentID, attribute string, value float64, max *float64) error {
+	data := map[string]interface{}{"attribute": attribute, "value": value}
+	if max != nil {
+		data["max"] = *max
+	}
+	return tx.stage(transactionOperation{
+		Action: "increment", DatabaseID: databaseID, CollectionID: collectionID, DocumentID: documentID, Data: data,
+	})
+}
+
+// DecrementDocumentAttribute stages an atomic decrement of a numeric attribute, bounded
+// by min when it is not nil.
+func (tx *Transaction) DecrementDocumentAttribute(databaseID, collectionID, documentID, attribute string, value float64, min *float64) error {
+	data := map[string]interface{}{"attribute": attribute, "value": value}
+	if min != nil {
+		data["min"] = *min
+	}
+	return tx.stage(transactionOperation{
+		Action: "decrement", DatabaseID: databaseID, CollectionID: collectionID, DocumentID: documentID, Data: data,
+	})
+}
+
+// Commit sends the staged operations and commits the transaction. When sending or
+// committing fails the transaction is rolled back, so it does not stay open on the
+// server until it expires.
+func (tx *Transaction) Commit() error {
+	return tx.CommitCtx(context.Background())
+}
+
+// CommitCtx is like Commit but uses ctx for the requests and cache calls.
+func (tx *Transaction) CommitCtx(ctx context.Context) (err error) {
+	ops, err := tx.finish()
+	if err != nil {
+		return err
+	}
+
+	op := newOperation("databases", "commitTransaction", "transactionId", tx.ID)
+	ctx, end := tx.db.Client.startOperation(ctx, op)
+	defer func() { end(err) }()
+
+	path := fmt.Sprintf("/databases/transactions/%s", tx.ID)
+	if len(ops) > 0 {
+		opsOp := newOperation("databases", "createOperations", "transactionId", tx.ID)
+		if _, err = tx.db.Client.sendRequest(ctx, opsOp, "POST", path+"/operations", map[string]interface{}{"operations": ops}); err != nil {
+			_ = tx.rollback(ctx)
+			return err
+		}
+	}
+	if _, err = tx.db.Client.sendRequest(ctx, op, "PATCH", path, map[string]interface{}{"commit": true}); err != nil {
+		// A transaction the server already discarded is not found.
+		if rbErr := tx.rollback(ctx); rbErr != nil && !IsNotFound(rbErr) {
+			err = errors.Join(err, rbErr)
+		}
+		return err
+	}
+
+	for _, o := range ops {
+		if o.DocumentID == "" || o.DocumentID == "unique()" {
+			tx.db.invalidateCollectionCache(ctx, o.DatabaseID, o.CollectionID)
+			continue
+		}
+		tx.db.invalidateDocumentCache(ctx, o.DatabaseID, o.CollectionID, o.DocumentID)
+	}
+	return nil
+}
+
+// Rollback discards the transaction and its staged operations.
+func (tx *Transaction) Rollback() error {
+	return tx.RollbackCtx(context.Background())
+}
+
+// RollbackCtx is like Rollback but uses ctx for the request.
+func (tx *Transaction) RollbackCtx(ctx context.Context) error {
+	if _, err := tx.finish(); err != nil {
+		return err
+	}
+	return tx.rollback(ctx)
+}
+
+func (tx *Transaction) rollback(ctx context.Context) error {
+	op := newOperation("databases", "rollbackTransaction", "transactionId", tx.ID)
+	path := fmt.Sprintf("/databases/transactions/%s", tx.ID)
+	_, err := tx.db.Client.sendRequest(ctx, op, "PATCH", path, map[string]interface{}{"rollback": true})
+	return err
+}
+
+// finish marks the transaction done and returns the staged operations.
+func (tx *Transaction) finish() ([]transactionOperation, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return nil, ErrTransactionDone
+	}
+	tx.done = true
+	return tx.ops, nil
+}
+
+// withPermissions returns data with the $permissions key set when permissions is not nil.
+func withPermissions(data map[string]interface{}, permissions []string) map[string]interface{} {
+	if permissions == nil {
+		return data
+	}
+	out := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		out[k] = v
+	}
+	out["$permissions"] = permissions
+	return out
+}
@@ -0,0 +1,192 @@
+package gowrite_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dm-vev/gowrite"
+)
+
+// memoryCache is an in-memory cache.Cache.
+type memoryCache struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data[key], nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key, value string, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+	return nil
+}
+
+func (c *memoryCache) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range keys {
+		delete(c.data, k)
+	}
+	return nil
+}
+
+func TestWithTransaction(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+		staged   []map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, fmt.Sprintf("%s %s %v", r.Method, r.URL.Path, body))
+		switch r.URL.Path {
+		case "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.8.0"}`)
+		case "/v1/databases/transactions":
+			fmt.Fprint(w, `{"$id":"tx1","status":"pending","expiresAt":"2025-01-01T00:05:00.000+00:00"}`)
+		case "/v1/databases/transactions/tx1/operations":
+			for _, op := range body["operations"].([]interface{}) {
+				staged = append(staged, op.(map[string]interface{}))
+			}
+			fmt.Fprint(w, `{"$id":"tx1"}`)
+		case "/v1/databases/db/collections/c/documents/stock":
+			fmt.Fprint(w, `{"$id":"stock","count":5}`)
+		default:
+			fmt.Fprint(w, `{"$id":"tx1"}`)
+		}
+	}))
+	defer srv.Close()
+
+	mc := &memoryCache{data: map[string]string{}}
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k")).WithCache(mc, time.Minute)
+	if _, err := db.GetDocument("db", "c", "stock"); err != nil {
+		t.Fatalf("GetDocument: %v", err)
+	}
+	cached := len(mc.data)
+
+	err := db.WithTransaction(context.Background(), func(tx *gowrite.Transaction) error {
+		tx.CreateDocument("db", "orders", "o1", map[string]interface{}{"total": 10}, nil)
+		tx.DecrementDocumentAttribute("db", "c", "stock", "count", 1, nil)
+		if len(mc.data) != cached {
+			t.Error("cache invalidated before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	if len(staged) != 2 || staged[0]["action"] != "create" || staged[1]["action"] != "decrement" {
+		t.Fatalf("staged operations = %v", staged)
+	}
+	if last := requests[len(requests)-1]; last != "PATCH /v1/databases/transactions/tx1 map[commit:true]" {
+		t.Fatalf("last request = %s", last)
+	}
+	if len(mc.data) == cached {
+		t.Error("cached document not invalidated after commit")
+	}
+
+	requests, staged = nil, nil
+	boom := errors.New("boom")
+	err = db.WithTransaction(context.Background(), func(tx *gowrite.Transaction) error {
+		tx.DeleteDocument("db", "c", "stock")
+		return boom
+	})
+	if !errors.Is(err, boom) || len(staged) != 0 {
+		t.Fatalf("err = %v, staged = %v", err, staged)
+	}
+	if last := requests[len(requests)-1]; last != "PATCH /v1/databases/transactions/tx1 map[rollback:true]" {
+		t.Fatalf("last request = %s", last)
+	}
+}
+
+func TestWithTransactionCommitFailure(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, fmt.Sprintf("%s %s %v", r.Method, r.URL.Path, body))
+		switch {
+		case r.URL.Path == "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.8.0"}`)
+		case body["commit"] == true:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":"conflict in transaction","code":400,"type":"transaction_conflict"}`)
+		case body["rollback"] == true:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"transaction not found","code":404,"type":"transaction_not_found"}`)
+		default:
+			fmt.Fprint(w, `{"$id":"tx1","expiresAt":"2025-01-01T00:05:00.000+00:00"}`)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	err := db.WithTransaction(context.Background(), func(tx *gowrite.Transaction) error {
+		return tx.DeleteDocument("db", "c", "stock")
+	})
+	appErr, ok := gowrite.AsAppwriteError(err)
+	if !ok || appErr.Type != "transaction_conflict" || gowrite.IsNotFound(err) {
+		t.Fatalf("err = %v, want only the commit error", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if last := requests[len(requests)-1]; last != "PATCH /v1/databases/transactions/tx1 map[rollback:true]" {
+		t.Fatalf("last request = %s", last)
+	}
+}
+
+func TestWithTransactionPanic(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, fmt.Sprintf("%s %s %v", r.Method, r.URL.Path, body))
+		switch r.URL.Path {
+		case "/v1/health/version":
+			fmt.Fprint(w, `{"version":"1.8.0"}`)
+		default:
+			fmt.Fprint(w, `{"$id":"tx1","expiresAt":"2025-01-01T00:05:00.000+00:00"}`)
+		}
+	}))
+	defer srv.Close()
+
+	db := gowrite.NewDatabases(gowrite.NewClient(srv.URL, "p", "k"))
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("recovered %v, want the panic of fn", p)
+		}
+		if last := requests[len(requests)-1]; last != "PATCH /v1/databases/transactions/tx1 map[rollback:true]" {
+			t.Fatalf("last request = %s", last)
+		}
+	}()
+	db.WithTransaction(context.Background(), func(tx *gowrite.Transaction) error {
+		tx.DeleteDocument("db", "c", "stock")
+		panic("boom")
+	})
+	t.Fatal("WithTransaction returned after fn panicked")
+}
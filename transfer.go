@@ -0,0 +1,124 @@
+package gowrite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+// uploadChunkSize — размер части, которыми Appwrite принимает файлы больше 5 МиБ.
+const uploadChunkSize = 5 << 20
+
+// CreateFileFromStream загружает в бакет файл с именем name, читая из r ровно size
+// байт. Файлы больше 5 МиБ отправляются частями по 5 МиБ с заголовками Content-Range
+// и x-appwrite-id, поэтому в памяти держится не больше одной части.
+//
+// Части, которые дописываются к уже созданному файлу, при сбое повторяются по
+// политике клиента. Первая часть создаёт файл и, как любой POST, повторяется только
+// с RetryNonIdempotent: после потерянного ответа повтор оставил бы лишний файл.
+func (s *StorageService) CreateFileFromStream(bucketID, fileID, name string, r io.Reader, size int64, permissions []string) (*File, error) {
+	return s.CreateFileFromStreamCtx(context.Background(), bucketID, fileID, name, r, size, permissions)
+}
+
+// CreateFileFromStreamCtx работает как CreateFileFromStream, но использует ctx для запросов.
+func (s *StorageService) CreateFileFromStreamCtx(ctx context.Context, bucketID, fileID, name string, r io.Reader, size int64, permissions []string) (*File, error) {
+	path := fmt.Sprintf("/storage/buckets/%s/files", bucketID)
+	op := newOperation("storage", "createFile", "bucketId", bucketID, "fileId", fileID)
+
+	chunk := make([]byte, min(size, uploadChunkSize))
+	var file *File
+	for start := int64(0); ; {
+		n := min(size-start, uploadChunkSize)
+		if _, err := io.ReadFull(r, chunk[:n]); err != nil {
+			return nil, err
+		}
+
+		// Часть собирается в памяти, чтобы её можно было перечитать при повторной попытке.
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		if err := writer.WriteField("fileId", fileID); err != nil {
+			return nil, err
+		}
+		for _, permission := range permissions {
+			if err := writer.WriteField("permissions[]", permission); err != nil {
+				return nil, err
+			}
+		}
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(chunk[:n]); err != nil {
+			return nil, err
+		}
+		writer.Close()
+
+		reqCtx := ctx
+		if file != nil {
+			// Повтор дописывает тот же диапазон байт в уже созданный файл.
+			reqCtx = context.WithValue(ctx, retryableKey{}, true)
+		}
+		req, err := s.Client.newRequest(reqCtx, "POST", path, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if size > uploadChunkSize {
+			req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+n-1, size))
+			if file != nil {
+				// Следующие части дописываются к файлу, созданному первой.
+				req.Header.Set("X-Appwrite-ID", file.ID)
+			}
+		}
+
+		respBody, err := s.Client.do(op, req)
+		if err != nil {
+			return nil, err
+		}
+		file = &File{}
+		if err := json.Unmarshal(respBody, file); err != nil {
+			return nil, err
+		}
+
+		if start += n; start >= size {
+			return file, nil
+		}
+	}
+}
+
+// DownloadFileTo скачивает файл в w, не загружая его в память целиком, и возвращает
+// число записанных байт. Если соединение оборвалось после начала записи, запрос не
+// повторяется, так как часть содержимого уже попала в w.
+func (s *StorageService) DownloadFileTo(bucketID, fileID string, w io.Writer) (int64, error) {
+	return s.DownloadFileToCtx(context.Background(), bucketID, fileID, w)
+}
+
+// DownloadFileToCtx работает как DownloadFileTo, но использует ctx для запроса.
+func (s *StorageService) DownloadFileToCtx(ctx context.Context, bucketID, fileID string, w io.Writer) (int64, error) {
+	path := fmt.Sprintf("/storage/buckets/%s/files/%s/download", bucketID, fileID)
+
+	op := newOperation("storage", "getFileDownload", "bucketId", bucketID, "fileId", fileID)
+	cw := &countingWriter{w: w}
+	req, err := s.Client.newRequest(context.WithValue(ctx, bodyWriterKey{}, io.Writer(cw)), "GET", path, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.Client.do(op, req)
+	return cw.n, err
+}
+
+// countingWriter считает байты, записанные в w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+package gowrite_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+)
+
+func TestCreateFileInChunks(t *testing.T) {
+	const size = 11<<20 + 3
+	var (
+		requests []string
+		received bytes.Buffer
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("FormFile: %v", err)
+			return
+		}
+		chunk, _ := io.ReadAll(file)
+		requests = append(requests, fmt.Sprintf("%s id=%q %d", r.Header.Get("Content-Range"), r.Header.Get("X-Appwrite-ID"), len(chunk)))
+		if len(requests) == 2 {
+			// The second chunk fails once and is sent again on its own.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received.Write(chunk)
+		fmt.Fprint(w, `{"$id":"file","bucketId":"bucket","name":"big.bin"}`)
+	}))
+	defer srv.Close()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+	// A reader without a known length is staged before the upload.
+	r := io.MultiReader(bytes.NewReader(content))
+	storage := gowrite.NewStorage(gowrite.NewClient(srv.URL, "project", "key").WithRetry(fastRetry()))
+	if _, err := storage.CreateFileFromReader("bucket", "file", "big.bin", r, nil); err != nil {
+		t.Fatalf("CreateFileFromReader: %v", err)
+	}
+	want := []string{
+		`bytes 0-5242879/11534339 id="" 5242880`,
+		`bytes 5242880-10485759/11534339 id="file" 5242880`,
+		`bytes 5242880-10485759/11534339 id="file" 5242880`,
+		`bytes 10485760-11534338/11534339 id="file" 1048579`,
+	}
+	if fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Fatalf("requests = %q, want %q", requests, want)
+	}
+	if !bytes.Equal(received.Bytes(), content) {
+		t.Fatal("uploaded content differs")
+	}
+}
+
+func TestDownloadFileTo(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "file content")
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	storage := gowrite.NewStorage(gowrite.NewClient(srv.URL, "project", "key").WithRetry(fastRetry()))
+	n, err := storage.DownloadFileTo("bucket", "file", &buf)
+	if err != nil || n != 12 || buf.String() != "file content" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("DownloadFileTo = %d, %v; wrote %q in %d calls", n, err, buf.String(), calls)
+	}
+}
+
+func TestCreateFileFirstChunkNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		// The server may have stored the chunk before failing, so repeating it could
+		// create a second file.
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	content := make([]byte, 6<<20)
+	storage := gowrite.NewStorage(gowrite.NewClient(srv.URL, "project", "key").WithRetry(fastRetry()))
+	if _, err := storage.CreateFileFromStream("bucket", "file", "big.bin", bytes.NewReader(content), int64(len(content)), nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("first chunk attempted %d times, want 1", n)
+	}
+}
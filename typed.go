@@ -0,0 +1,258 @@
+package gowrite
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DocumentMeta holds the system fields of a document. Embed it in a struct to receive
+// them from the typed helpers:
+//
+//	type Post struct {
+//		gowrite.DocumentMeta
+//		Title  string                 `json:"title"`
+//		Author string                 `json:"author"` // relationship kept as ID
+//		Tags   []Tag                  `json:"tags"`   // relationship as nested documents
+//		Extra  map[string]interface{} `json:"-" gowrite:"extra"`
+//	}
+//
+// Any other struct field can be mapped to a system field with a json tag, e.g.
+// `json:"$id"`. A map[string]interface{} field tagged `gowrite:"extra"` receives the
+// attributes without a matching field and is merged back into the payload on create.
+type DocumentMeta struct {
+	ID          string     `json:"$id,omitempty"`
+	Sequence    int64      `json:"$sequence,omitempty"`
+	Collection  string     `json:"$collectionId,omitempty"`
+	Database    string     `json:"$databaseId,omitempty"`
+	CreatedAt   *time.Time `json:"$createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"$updatedAt,omitempty"`
+	Permissions []string   `json:"$permissions,omitempty"`
+}
+
+// GetDocumentAs retrieves a document and decodes it into T.
+func GetDocumentAs[T any](ctx context.Context, db *DatabaseService, databaseID, collectionID, documentID string) (*T, error) {
+	doc, err := db.GetDocumentCtx(ctx, databaseID, collectionID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDocument[T](doc)
+}
+
+// ListDocumentsAs lists documents like ListDocuments and decodes each into T.
+func ListDocumentsAs[T any](ctx context.Context, db *DatabaseService, databaseID, collectionID string, queries []string) ([]*T, error) {
+	docs, err := db.ListDocumentsCtx(ctx, databaseID, collectionID, queries)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*T, 0, len(docs))
+	for _, doc := range docs {
+		v, err := DecodeDocument[T](doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// CreateDocumentFrom creates a document from v and returns the stored document decoded
+// into T. An empty documentID or nil permissions fall back to the $id and $permissions
+// fields of v; without either a unique ID is generated by the server. Nested structs
+// are sent as nested documents, so relationships that should only be linked are best
+// modelled as ID strings.
+func CreateDocumentFrom[T any](ctx context.Context, db *DatabaseService, databaseID, collectionID, documentID string, v *T, permissions []string) (*T, error) {
+	id, data, perms, err := EncodeDocument(v)
+	if err != nil {
+		return nil, err
+	}
+	if documentID == "" {
+		documentID = id
+	}
+	if documentID == "" {
+		documentID = "unique()"
+	}
+	if permissions == nil {
+		permissions = perms
+	}
+
+	doc, err := db.CreateDocumentCtx(ctx, databaseID, collectionID, documentID, data, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDocument[T](doc)
+}
+
+// DecodeDocument converts a document into T. Relationship attributes are adapted to the
+// field type: a nested document assigned to a string field yields its $id, and an ID
+// assigned to a struct field yields a struct with only the ID set.
+func DecodeDocument[T any](doc *Document) (*T, error) {
+	m := make(map[string]interface{}, len(doc.Data)+7)
+	for k, v := range doc.Data {
+		m[k] = v
+	}
+	m["$id"] = doc.ID
+	m["$sequence"] = doc.Sequence
+	m["$collectionId"] = doc.Collection
+	m["$databaseId"] = doc.Database
+	m["$permissions"] = doc.Permissions
+	if !doc.CreatedAt.IsZero() {
+		m["$createdAt"] = formatTimestamp(doc.CreatedAt)
+	}
+	if !doc.UpdatedAt.IsZero() {
+		m["$updatedAt"] = formatTimestamp(doc.UpdatedAt)
+	}
+
+	var out T
+	rv := reflect.ValueOf(&out).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, &json.UnsupportedTypeError{Type: rv.Type()}
+	}
+
+	fields := typedFields(rv.Type())
+	adapted := adaptValue(m, rv.Type()).(map[string]interface{})
+	b, err := json.Marshal(adapted)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+
+	if fields.extra != nil {
+		extra := make(map[string]interface{})
+		for k, v := range doc.Data {
+			if _, known := fields.byName[strings.ToLower(k)]; !known {
+				extra[k] = v
+			}
+		}
+		rv.FieldByIndex(fields.extra).Set(reflect.ValueOf(extra))
+	}
+	return &out, nil
+}
+
+// EncodeDocument splits v into its document ID, attribute data and permissions.
+func EncodeDocument[T any](v *T) (string, map[string]interface{}, []string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return "", nil, nil, err
+	}
+
+	id, _ := data["$id"].(string)
+	var permissions []string
+	if raw, ok := data["$permissions"].([]interface{}); ok {
+		for _, p := range raw {
+			if s, ok := p.(string); ok {
+				permissions = append(permissions, s)
+			}
+		}
+	}
+	for k := range data {
+		if strings.HasPrefix(k, "$") {
+			delete(data, k)
+		}
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	if fields := typedFields(rv.Type()); fields.extra != nil {
+		extra, _ := rv.FieldByIndex(fields.extra).Interface().(map[string]interface{})
+		for k, val := range extra {
+			if _, exists := data[k]; !exists {
+				data[k] = val
+			}
+		}
+	}
+	return id, data, permissions, nil
+}
+
+// structFields describes the JSON names of a struct type.
+type structFields struct {
+	// byName maps lower-cased JSON names to field types.
+	byName map[string]reflect.Type
+	// extra is the index of the field tagged `gowrite:"extra"`, if any.
+	extra []int
+}
+
+func typedFields(t reflect.Type) structFields {
+	fields := structFields{byName: make(map[string]reflect.Type)}
+	collectFields(t, nil, &fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, index []int, fields *structFields) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+		if f.Tag.Get("gowrite") == "extra" {
+			if f.Type == reflect.TypeOf(map[string]interface{}(nil)) {
+				fields.extra = idx
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			collectFields(f.Type, idx, fields)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields.byName[strings.ToLower(name)] = f.Type
+	}
+}
+
+// adaptValue reshapes relationship values in v to fit the Go type t.
+func adaptValue(v interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if obj, ok := v.(map[string]interface{}); ok {
+			if id, ok := obj["$id"].(string); ok {
+				return id
+			}
+		}
+	case reflect.Slice:
+		if list, ok := v.([]interface{}); ok {
+			out := make([]interface{}, len(list))
+			for i, item := range list {
+				out[i] = adaptValue(item, t.Elem())
+			}
+			return out
+		}
+	case reflect.Struct:
+		if id, ok := v.(string); ok && t != reflect.TypeOf(DocumentMeta{}) && !isTimeType(t) {
+			return map[string]interface{}{"$id": id}
+		}
+		if obj, ok := v.(map[string]interface{}); ok {
+			fields := typedFields(t)
+			out := make(map[string]interface{}, len(obj))
+			for k, val := range obj {
+				if ft, ok := fields.byName[strings.ToLower(k)]; ok {
+					val = adaptValue(val, ft)
+				}
+				out[k] = val
+			}
+			return out
+		}
+	}
+	return v
+}
+
+func isTimeType(t reflect.Type) bool {
+	return t.PkgPath() == "time" && t.Name() == "Time"
+}
@@ -0,0 +1,83 @@
+package gowrite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dm-vev/gowrite"
+)
+
+type tag struct {
+	gowrite.DocumentMeta
+	Name string `json:"name"`
+}
+
+type post struct {
+	gowrite.DocumentMeta
+	Title  string                 `json:"title"`
+	Views  int                    `json:"views"`
+	Author string                 `json:"author"`
+	Tags   []tag                  `json:"tags"`
+	Editor *tag                   `json:"editor"`
+	Extra  map[string]interface{} `json:"-" gowrite:"extra"`
+}
+
+func TestDecodeDocument(t *testing.T) {
+	doc := &gowrite.Document{
+		ID:          "p1",
+		Collection:  "posts",
+		Database:    "blog",
+		Permissions: []string{gowrite.ReadAny},
+		Data: map[string]interface{}{
+			"title":  "Hello",
+			"views":  float64(42),
+			"author": map[string]interface{}{"$id": "u1", "name": "Ann"},
+			"tags":   []interface{}{"t1", map[string]interface{}{"$id": "t2", "name": "go"}},
+			"editor": "u2",
+			"draft":  true,
+		},
+	}
+
+	p, err := gowrite.DecodeDocument[post](doc)
+	if err != nil {
+		t.Fatalf("DecodeDocument: %v", err)
+	}
+	if p.ID != "p1" || p.Collection != "posts" || p.Title != "Hello" || p.Views != 42 {
+		t.Fatalf("unexpected fields: %+v", p)
+	}
+	if p.Author != "u1" {
+		t.Errorf("Author = %q, want the related document ID", p.Author)
+	}
+	if len(p.Tags) != 2 || p.Tags[0].ID != "t1" || p.Tags[1].Name != "go" {
+		t.Errorf("Tags = %+v", p.Tags)
+	}
+	if p.Editor == nil || p.Editor.ID != "u2" {
+		t.Errorf("Editor = %+v", p.Editor)
+	}
+	if !reflect.DeepEqual(p.Extra, map[string]interface{}{"draft": true}) {
+		t.Errorf("Extra = %v", p.Extra)
+	}
+}
+
+func TestEncodeDocument(t *testing.T) {
+	p := &post{
+		DocumentMeta: gowrite.DocumentMeta{ID: "p1", Permissions: []string{gowrite.ReadAny}},
+		Title:        "Hello",
+		Author:       "u1",
+		Extra:        map[string]interface{}{"draft": true},
+	}
+
+	id, data, perms, err := gowrite.EncodeDocument(p)
+	if err != nil {
+		t.Fatalf("EncodeDocument: %v", err)
+	}
+	if id != "p1" || !reflect.DeepEqual(perms, []string{gowrite.ReadAny}) {
+		t.Fatalf("id=%q perms=%v", id, perms)
+	}
+	if _, ok := data["$id"]; ok {
+		t.Error("system fields must not be part of the data")
+	}
+	if data["title"] != "Hello" || data["author"] != "u1" || data["draft"] != true {
+		t.Errorf("data = %v", data)
+	}
+}
@@ -1,9 +1,11 @@
 package gowrite
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"time"
 )
 
 type UsersService struct {
@@ -12,14 +14,22 @@ type UsersService struct {
 
 // User represents an Appwrite user.
 type User struct {
-	ID     string                 `json:"$id"`
-	Email  string                 `json:"email"`
-	Phone  string                 `json:"phone"`
-	Name   string                 `json:"name"`
-	Status bool                   `json:"status"`
-	Labels []string               `json:"labels"`
-	Mfa    bool                   `json:"mfa"`
-	Data   map[string]interface{} `json:"-"`
+	ID                string                 `json:"$id"`
+	CreatedAt         time.Time              `json:"$createdAt"`
+	UpdatedAt         time.Time              `json:"$updatedAt"`
+	Email             string                 `json:"email"`
+	Phone             string                 `json:"phone"`
+	Name              string                 `json:"name"`
+	Status            bool                   `json:"status"`
+	Labels            []string               `json:"labels"`
+	Mfa               bool                   `json:"mfa"`
+	EmailVerification bool                   `json:"emailVerification"`
+	PhoneVerification bool                   `json:"phoneVerification"`
+	Registration      time.Time              `json:"registration"`
+	PasswordUpdate    time.Time              `json:"passwordUpdate"`
+	AccessedAt        time.Time              `json:"accessedAt"`
+	Prefs             Preferences            `json:"prefs"`
+	Data              map[string]interface{} `json:"-"`
 }
 
 // Preferences represent user preferences.
@@ -31,14 +41,39 @@ func NewUsers(client *AppwriteClient) *UsersService {
 
 func (u *User) UnmarshalJSON(b []byte) error {
 	type Alias User
-	aux := &struct{ *Alias }{Alias: (*Alias)(u)}
+	aux := &struct {
+		*Alias
+		CreatedAt      string `json:"$createdAt"`
+		UpdatedAt      string `json:"$updatedAt"`
+		Registration   string `json:"registration"`
+		PasswordUpdate string `json:"passwordUpdate"`
+		AccessedAt     string `json:"accessedAt"`
+	}{Alias: (*Alias)(u)}
 	if err := json.Unmarshal(b, &aux); err != nil {
 		return err
 	}
+	for _, ts := range []struct {
+		dst *time.Time
+		src string
+	}{
+		{&u.CreatedAt, aux.CreatedAt},
+		{&u.UpdatedAt, aux.UpdatedAt},
+		{&u.Registration, aux.Registration},
+		{&u.PasswordUpdate, aux.PasswordUpdate},
+		{&u.AccessedAt, aux.AccessedAt},
+	} {
+		t, err := parseTimestamp(ts.src)
+		if err != nil {
+			return err
+		}
+		*ts.dst = t
+	}
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
+	// Fields typed after Data was introduced, such as prefs and the timestamps, stay
+	// in Data as well for the code that reads them from there.
 	delete(raw, "$id")
 	delete(raw, "email")
 	delete(raw, "phone")
@@ -52,6 +87,11 @@ func (u *User) UnmarshalJSON(b []byte) error {
 
 // CreateUser creates a new user with a plain text password.
 func (s *UsersService) CreateUser(userID, email, phone, password, name string) (*User, error) {
+	return s.CreateUserCtx(context.Background(), userID, email, phone, password, name)
+}
+
+// CreateUserCtx is like CreateUser but uses ctx for the request.
+func (s *UsersService) CreateUserCtx(ctx context.Context, userID, email, phone, password, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":   userID,
 		"email":    email,
@@ -59,7 +99,8 @@ func (s *UsersService) CreateUser(userID, email, phone, password, name string) (
 		"password": password,
 		"name":     name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users", payload)
+	op := newOperation("users", "create", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -71,10 +112,16 @@ func (s *UsersService) CreateUser(userID, email, phone, password, name string) (
 }
 
 func (s *UsersService) CreateAnonymousUser(userID string) (*User, error) {
+	return s.CreateAnonymousUserCtx(context.Background(), userID)
+}
+
+// CreateAnonymousUserCtx is like CreateAnonymousUser but uses ctx for the request.
+func (s *UsersService) CreateAnonymousUserCtx(ctx context.Context, userID string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId": userID,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users", payload)
+	op := newOperation("users", "create", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -87,13 +134,19 @@ func (s *UsersService) CreateAnonymousUser(userID string) (*User, error) {
 
 // CreateArgon2User creates a user with Argon2 hashed password.
 func (s *UsersService) CreateArgon2User(userID, email, password, name string) (*User, error) {
+	return s.CreateArgon2UserCtx(context.Background(), userID, email, password, name)
+}
+
+// CreateArgon2UserCtx is like CreateArgon2User but uses ctx for the request.
+func (s *UsersService) CreateArgon2UserCtx(ctx context.Context, userID, email, password, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":   userID,
 		"email":    email,
 		"password": password,
 		"name":     name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/argon2", payload)
+	op := newOperation("users", "createArgon2User", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/argon2", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -106,13 +159,19 @@ func (s *UsersService) CreateArgon2User(userID, email, password, name string) (*
 
 // CreateBcryptUser creates a user with Bcrypt hashed password.
 func (s *UsersService) CreateBcryptUser(userID, email, password, name string) (*User, error) {
+	return s.CreateBcryptUserCtx(context.Background(), userID, email, password, name)
+}
+
+// CreateBcryptUserCtx is like CreateBcryptUser but uses ctx for the request.
+func (s *UsersService) CreateBcryptUserCtx(ctx context.Context, userID, email, password, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":   userID,
 		"email":    email,
 		"password": password,
 		"name":     name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/bcrypt", payload)
+	op := newOperation("users", "createBcryptUser", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/bcrypt", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -125,13 +184,19 @@ func (s *UsersService) CreateBcryptUser(userID, email, password, name string) (*
 
 // CreateMD5User creates a user with MD5 hashed password.
 func (s *UsersService) CreateMD5User(userID, email, password, name string) (*User, error) {
+	return s.CreateMD5UserCtx(context.Background(), userID, email, password, name)
+}
+
+// CreateMD5UserCtx is like CreateMD5User but uses ctx for the request.
+func (s *UsersService) CreateMD5UserCtx(ctx context.Context, userID, email, password, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":   userID,
 		"email":    email,
 		"password": password,
 		"name":     name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/md5", payload)
+	op := newOperation("users", "createMD5User", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/md5", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -144,13 +209,19 @@ func (s *UsersService) CreateMD5User(userID, email, password, name string) (*Use
 
 // CreatePHPassUser creates a user with PHPass hashed password.
 func (s *UsersService) CreatePHPassUser(userID, email, password, name string) (*User, error) {
+	return s.CreatePHPassUserCtx(context.Background(), userID, email, password, name)
+}
+
+// CreatePHPassUserCtx is like CreatePHPassUser but uses ctx for the request.
+func (s *UsersService) CreatePHPassUserCtx(ctx context.Context, userID, email, password, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":   userID,
 		"email":    email,
 		"password": password,
 		"name":     name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/phpass", payload)
+	op := newOperation("users", "createPHPassUser", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/phpass", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -163,6 +234,11 @@ func (s *UsersService) CreatePHPassUser(userID, email, password, name string) (*
 
 // CreateScryptModifiedUser creates a user with Scrypt Modified hashed password.
 func (s *UsersService) CreateScryptModifiedUser(userID, email, password, passwordSalt, passwordSaltSeparator, passwordSignerKey, name string) (*User, error) {
+	return s.CreateScryptModifiedUserCtx(context.Background(), userID, email, password, passwordSalt, passwordSaltSeparator, passwordSignerKey, name)
+}
+
+// CreateScryptModifiedUserCtx is like CreateScryptModifiedUser but uses ctx for the request.
+func (s *UsersService) CreateScryptModifiedUserCtx(ctx context.Context, userID, email, password, passwordSalt, passwordSaltSeparator, passwordSignerKey, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":                userID,
 		"email":                 email,
@@ -172,7 +248,8 @@ func (s *UsersService) CreateScryptModifiedUser(userID, email, password, passwor
 		"passwordSignerKey":     passwordSignerKey,
 		"name":                  name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/scrypt-modified", payload)
+	op := newOperation("users", "createScryptModifiedUser", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/scrypt-modified", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -185,6 +262,11 @@ func (s *UsersService) CreateScryptModifiedUser(userID, email, password, passwor
 
 // CreateScryptUser creates a user with Scrypt hashed password.
 func (s *UsersService) CreateScryptUser(userID, email, password, passwordSalt string, passwordCpu, passwordMemory, passwordParallel, passwordLength int, name string) (*User, error) {
+	return s.CreateScryptUserCtx(context.Background(), userID, email, password, passwordSalt, passwordCpu, passwordMemory, passwordParallel, passwordLength, name)
+}
+
+// CreateScryptUserCtx is like CreateScryptUser but uses ctx for the request.
+func (s *UsersService) CreateScryptUserCtx(ctx context.Context, userID, email, password, passwordSalt string, passwordCpu, passwordMemory, passwordParallel, passwordLength int, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":           userID,
 		"email":            email,
@@ -196,7 +278,8 @@ func (s *UsersService) CreateScryptUser(userID, email, password, passwordSalt st
 		"passwordLength":   passwordLength,
 		"name":             name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/scrypt", payload)
+	op := newOperation("users", "createScryptUser", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/scrypt", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +292,11 @@ func (s *UsersService) CreateScryptUser(userID, email, password, passwordSalt st
 
 // CreateSHAUser creates a user with SHA hashed password.
 func (s *UsersService) CreateSHAUser(userID, email, password, passwordVersion, name string) (*User, error) {
+	return s.CreateSHAUserCtx(context.Background(), userID, email, password, passwordVersion, name)
+}
+
+// CreateSHAUserCtx is like CreateSHAUser but uses ctx for the request.
+func (s *UsersService) CreateSHAUserCtx(ctx context.Context, userID, email, password, passwordVersion, name string) (*User, error) {
 	payload := map[string]interface{}{
 		"userId":          userID,
 		"email":           email,
@@ -216,7 +304,8 @@ func (s *UsersService) CreateSHAUser(userID, email, password, passwordVersion, n
 		"passwordVersion": passwordVersion,
 		"name":            name,
 	}
-	resp, err := s.Client.sendRequest("POST", "/users/sha", payload)
+	op := newOperation("users", "createSHAUser", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "POST", "/users/sha", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -229,8 +318,14 @@ func (s *UsersService) CreateSHAUser(userID, email, password, passwordVersion, n
 
 // GetUser retrieves a user by ID.
 func (s *UsersService) GetUser(userID string) (*User, error) {
+	return s.GetUserCtx(context.Background(), userID)
+}
+
+// GetUserCtx is like GetUser but uses ctx for the request.
+func (s *UsersService) GetUserCtx(ctx context.Context, userID string) (*User, error) {
 	path := fmt.Sprintf("/users/%s", userID)
-	resp, err := s.Client.sendRequest("GET", path, nil)
+	op := newOperation("users", "get", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -243,8 +338,14 @@ func (s *UsersService) GetUser(userID string) (*User, error) {
 
 // GetUserPreferences retrieves user preferences.
 func (s *UsersService) GetUserPreferences(userID string) (Preferences, error) {
+	return s.GetUserPreferencesCtx(context.Background(), userID)
+}
+
+// GetUserPreferencesCtx is like GetUserPreferences but uses ctx for the request.
+func (s *UsersService) GetUserPreferencesCtx(ctx context.Context, userID string) (Preferences, error) {
 	path := fmt.Sprintf("/users/%s/prefs", userID)
-	resp, err := s.Client.sendRequest("GET", path, nil)
+	op := newOperation("users", "getPrefs", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -257,6 +358,11 @@ func (s *UsersService) GetUserPreferences(userID string) (Preferences, error) {
 
 // ListUsers lists project users.
 func (s *UsersService) ListUsers(queries []string, search string) ([]*User, error) {
+	return s.ListUsersCtx(context.Background(), queries, search)
+}
+
+// ListUsersCtx is like ListUsers but uses ctx for the request.
+func (s *UsersService) ListUsersCtx(ctx context.Context, queries []string, search string) ([]*User, error) {
 	q := url.Values{}
 	for _, qs := range queries {
 		q.Add("queries[]", qs)
@@ -268,7 +374,8 @@ func (s *UsersService) ListUsers(queries []string, search string) ([]*User, erro
 	if encoded := q.Encode(); encoded != "" {
 		path += "?" + encoded
 	}
-	resp, err := s.Client.sendRequest("GET", path, nil)
+	op := newOperation("users", "list")
+	resp, err := s.Client.sendRequest(ctx, op, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -283,9 +390,15 @@ func (s *UsersService) ListUsers(queries []string, search string) ([]*User, erro
 
 // UpdateEmail updates user email.
 func (s *UsersService) UpdateEmail(userID, email string) (*User, error) {
+	return s.UpdateEmailCtx(context.Background(), userID, email)
+}
+
+// UpdateEmailCtx is like UpdateEmail but uses ctx for the request.
+func (s *UsersService) UpdateEmailCtx(ctx context.Context, userID, email string) (*User, error) {
 	payload := map[string]interface{}{"email": email}
 	path := fmt.Sprintf("/users/%s/email", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updateEmail", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -298,9 +411,15 @@ func (s *UsersService) UpdateEmail(userID, email string) (*User, error) {
 
 // UpdateEmailVerification sets user email verification status.
 func (s *UsersService) UpdateEmailVerification(userID string, verified bool) (*User, error) {
+	return s.UpdateEmailVerificationCtx(context.Background(), userID, verified)
+}
+
+// UpdateEmailVerificationCtx is like UpdateEmailVerification but uses ctx for the request.
+func (s *UsersService) UpdateEmailVerificationCtx(ctx context.Context, userID string, verified bool) (*User, error) {
 	payload := map[string]interface{}{"emailVerification": verified}
 	path := fmt.Sprintf("/users/%s/verification", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updateEmailVerification", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -313,9 +432,15 @@ func (s *UsersService) UpdateEmailVerification(userID string, verified bool) (*U
 
 // UpdateMFA enables or disables MFA for a user.
 func (s *UsersService) UpdateMFA(userID string, mfa bool) (*User, error) {
+	return s.UpdateMFACtx(context.Background(), userID, mfa)
+}
+
+// UpdateMFACtx is like UpdateMFA but uses ctx for the request.
+func (s *UsersService) UpdateMFACtx(ctx context.Context, userID string, mfa bool) (*User, error) {
 	payload := map[string]interface{}{"mfa": mfa}
 	path := fmt.Sprintf("/users/%s/mfa", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updateMfa", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -328,9 +453,15 @@ func (s *UsersService) UpdateMFA(userID string, mfa bool) (*User, error) {
 
 // UpdateName updates user name.
 func (s *UsersService) UpdateName(userID, name string) (*User, error) {
+	return s.UpdateNameCtx(context.Background(), userID, name)
+}
+
+// UpdateNameCtx is like UpdateName but uses ctx for the request.
+func (s *UsersService) UpdateNameCtx(ctx context.Context, userID, name string) (*User, error) {
 	payload := map[string]interface{}{"name": name}
 	path := fmt.Sprintf("/users/%s/name", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updateName", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -343,9 +474,15 @@ func (s *UsersService) UpdateName(userID, name string) (*User, error) {
 
 // UpdatePassword updates user password.
 func (s *UsersService) UpdatePassword(userID, password string) (*User, error) {
+	return s.UpdatePasswordCtx(context.Background(), userID, password)
+}
+
+// UpdatePasswordCtx is like UpdatePassword but uses ctx for the request.
+func (s *UsersService) UpdatePasswordCtx(ctx context.Context, userID, password string) (*User, error) {
 	payload := map[string]interface{}{"password": password}
 	path := fmt.Sprintf("/users/%s/password", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updatePassword", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -358,9 +495,15 @@ func (s *UsersService) UpdatePassword(userID, password string) (*User, error) {
 
 // UpdatePhone updates user phone number.
 func (s *UsersService) UpdatePhone(userID, number string) (*User, error) {
+	return s.UpdatePhoneCtx(context.Background(), userID, number)
+}
+
+// UpdatePhoneCtx is like UpdatePhone but uses ctx for the request.
+func (s *UsersService) UpdatePhoneCtx(ctx context.Context, userID, number string) (*User, error) {
 	payload := map[string]interface{}{"number": number}
 	path := fmt.Sprintf("/users/%s/phone", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updatePhone", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -373,9 +516,15 @@ func (s *UsersService) UpdatePhone(userID, number string) (*User, error) {
 
 // UpdatePhoneVerification sets phone verification status.
 func (s *UsersService) UpdatePhoneVerification(userID string, verified bool) (*User, error) {
+	return s.UpdatePhoneVerificationCtx(context.Background(), userID, verified)
+}
+
+// UpdatePhoneVerificationCtx is like UpdatePhoneVerification but uses ctx for the request.
+func (s *UsersService) UpdatePhoneVerificationCtx(ctx context.Context, userID string, verified bool) (*User, error) {
 	payload := map[string]interface{}{"phoneVerification": verified}
 	path := fmt.Sprintf("/users/%s/verification/phone", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updatePhoneVerification", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -388,9 +537,15 @@ func (s *UsersService) UpdatePhoneVerification(userID string, verified bool) (*U
 
 // UpdateUserLabels replaces user labels.
 func (s *UsersService) UpdateUserLabels(userID string, labels []string) (*User, error) {
+	return s.UpdateUserLabelsCtx(context.Background(), userID, labels)
+}
+
+// UpdateUserLabelsCtx is like UpdateUserLabels but uses ctx for the request.
+func (s *UsersService) UpdateUserLabelsCtx(ctx context.Context, userID string, labels []string) (*User, error) {
 	payload := map[string]interface{}{"labels": labels}
 	path := fmt.Sprintf("/users/%s/labels", userID)
-	resp, err := s.Client.sendRequest("PUT", path, payload)
+	op := newOperation("users", "updateLabels", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PUT", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -403,9 +558,15 @@ func (s *UsersService) UpdateUserLabels(userID string, labels []string) (*User,
 
 // UpdateUserPreferences sets user preferences.
 func (s *UsersService) UpdateUserPreferences(userID string, prefs Preferences) (Preferences, error) {
+	return s.UpdateUserPreferencesCtx(context.Background(), userID, prefs)
+}
+
+// UpdateUserPreferencesCtx is like UpdateUserPreferences but uses ctx for the request.
+func (s *UsersService) UpdateUserPreferencesCtx(ctx context.Context, userID string, prefs Preferences) (Preferences, error) {
 	payload := map[string]interface{}{"prefs": prefs}
 	path := fmt.Sprintf("/users/%s/prefs", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updatePrefs", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -418,9 +579,15 @@ func (s *UsersService) UpdateUserPreferences(userID string, prefs Preferences) (
 
 // UpdateUserStatus updates user status (enabled or disabled).
 func (s *UsersService) UpdateUserStatus(userID string, status bool) (*User, error) {
+	return s.UpdateUserStatusCtx(context.Background(), userID, status)
+}
+
+// UpdateUserStatusCtx is like UpdateUserStatus but uses ctx for the request.
+func (s *UsersService) UpdateUserStatusCtx(ctx context.Context, userID string, status bool) (*User, error) {
 	payload := map[string]interface{}{"status": status}
 	path := fmt.Sprintf("/users/%s/status", userID)
-	resp, err := s.Client.sendRequest("PATCH", path, payload)
+	op := newOperation("users", "updateStatus", "userId", userID)
+	resp, err := s.Client.sendRequest(ctx, op, "PATCH", path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -433,7 +600,13 @@ func (s *UsersService) UpdateUserStatus(userID string, status bool) (*User, erro
 
 // DeleteUser deletes a user by ID.
 func (s *UsersService) DeleteUser(userID string) error {
+	return s.DeleteUserCtx(context.Background(), userID)
+}
+
+// DeleteUserCtx is like DeleteUser but uses ctx for the request.
+func (s *UsersService) DeleteUserCtx(ctx context.Context, userID string) error {
 	path := fmt.Sprintf("/users/%s", userID)
-	_, err := s.Client.sendRequest("DELETE", path, nil)
+	op := newOperation("users", "delete", "userId", userID)
+	_, err := s.Client.sendRequest(ctx, op, "DELETE", path, nil)
 	return err
 }
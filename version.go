@@ -0,0 +1,178 @@
+package gowrite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResponseFormat is the response format requested when the client does not set
+// ResponseFormat. The models of this package follow it.
+const DefaultResponseFormat = "1.6.0"
+
+// DefaultVersionCacheTTL is how long a probed server version is reused when the client
+// does not set VersionCacheTTL.
+const DefaultVersionCacheTTL = 10 * time.Minute
+
+// versionRetryInterval is how long a failed probe is remembered before the server is
+// asked again.
+const versionRetryInterval = 30 * time.Second
+
+// versionCache holds the outcome of the last probe. It is created by NewClient and
+// shared by the copies of a client, which talk to the same server. A nil cache stores
+// nothing, so clients built without NewClient probe the server on every call.
+type versionCache struct {
+	mu      sync.Mutex
+	version string
+	err     error
+	expires time.Time
+}
+
+// load returns the cached outcome and whether it is still fresh.
+func (c *versionCache) load() (string, error, bool) {
+	if c == nil {
+		return "", nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version, c.err, time.Now().Before(c.expires)
+}
+
+func (c *versionCache) store(version string, err error, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version, c.err, c.expires = version, err, time.Now().Add(ttl)
+}
+
+// ServerVersion describes the Appwrite server behind the client's endpoint.
+type ServerVersion struct {
+	Version string `json:"version"`
+	// Warning is set when the server is compatible but newer than the requested
+	// response format, so responses are downgraded by the server.
+	Warning string `json:"-"`
+}
+
+// IncompatibleVersionError is returned by Probe when the server cannot serve the
+// client's response format.
+type IncompatibleVersionError struct {
+	ServerVersion  string
+	ResponseFormat string
+}
+
+func (e *IncompatibleVersionError) Error() string {
+	return fmt.Sprintf("appwrite server %s is incompatible with response format %s", e.ServerVersion, e.ResponseFormat)
+}
+
+// baseURL returns the endpoint without trailing slashes and without the /v1 suffix,
+// so both "https://host" and "https://host/v1/" are accepted.
+func (client *AppwriteClient) baseURL() string {
+	endpoint := strings.TrimRight(client.Endpoint, "/")
+	endpoint = strings.TrimSuffix(endpoint, "/v1")
+	return endpoint
+}
+
+func (client *AppwriteClient) responseFormat() string {
+	if client.ResponseFormat != "" {
+		return client.ResponseFormat
+	}
+	return DefaultResponseFormat
+}
+
+// Probe reads the server version from /health/version and checks it against the
+// response format. It returns an *IncompatibleVersionError, together with the version,
+// when the server is older than the response format or has a different major version.
+// The outcome is cached for ServerVersion.
+func (client *AppwriteClient) Probe(ctx context.Context) (*ServerVersion, error) {
+	op := newOperation("health", "getVersion")
+	respBody, err := client.sendRequest(ctx, op, "GET", "/health/version", nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			// A cancelled caller says nothing about the server.
+			client.storeVersion("", err)
+		}
+		return nil, err
+	}
+
+	var sv ServerVersion
+	if err = json.Unmarshal(respBody, &sv); err != nil {
+		client.storeVersion("", err)
+		return nil, err
+	}
+
+	format := client.responseFormat()
+	server, want := parseVersion(sv.Version), parseVersion(format)
+	if server[0] != want[0] || compareVersions(server, want) < 0 {
+		err := &IncompatibleVersionError{ServerVersion: sv.Version, ResponseFormat: format}
+		client.storeVersion(sv.Version, err)
+		return &sv, err
+	}
+	client.storeVersion(sv.Version, nil)
+	if server[1] > want[1] {
+		sv.Warning = fmt.Sprintf("appwrite server %s is newer than response format %s", sv.Version, format)
+	}
+	return &sv, nil
+}
+
+// ServerVersion returns the version of the server, probing it when the cached version
+// is older than VersionCacheTTL. Like Probe it returns an *IncompatibleVersionError
+// together with the version. Failed probes are cached for a short while, during which
+// their error is returned without contacting the server.
+func (client *AppwriteClient) ServerVersion(ctx context.Context) (string, error) {
+	if version, err, fresh := client.versions.load(); fresh {
+		return version, err
+	}
+	sv, err := client.Probe(ctx)
+	if sv != nil {
+		return sv.Version, err
+	}
+	return "", err
+}
+
+// ResetServerVersion discards the cached server version, so the next call depending on
+// it probes the server again, e.g. after the server was upgraded.
+func (client *AppwriteClient) ResetServerVersion() {
+	client.versions.store("", nil, 0)
+}
+
+func (client *AppwriteClient) storeVersion(version string, err error) {
+	ttl := client.VersionCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultVersionCacheTTL
+	}
+	if version == "" {
+		ttl = min(ttl, versionRetryInterval)
+	}
+	client.versions.store(version, err, ttl)
+}
+
+// parseVersion parses "1.6.2" or "1.7.0-rc1" into major, minor and patch.
+func parseVersion(v string) [3]int {
+	var out [3]int
+	for i, part := range strings.SplitN(v, ".", 3) {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		out[i], _ = strconv.Atoi(part[:end])
+	}
+	return out
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}